@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Lavizord/checkers-server/config"
+	"github.com/Lavizord/checkers-server/engine"
+	"github.com/Lavizord/checkers-server/messages"
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/Lavizord/checkers-server/redisdb"
+)
+
+var pid int
+var redisClient *redisdb.RedisClient
+var name = "BotWorker"
+var consumer = redisdb.ConsumerName()
+
+// Bots are stored in Redis until their game is over, and each one is run by the botworker holding its
+// lease. The leases of a botworker that stops expire, and the other botworkers adopt its bots.
+const (
+	botLease        = 30 * time.Second
+	botLeaseRenewal = botLease / 3
+)
+
+// bots are the bots run by this botworker.
+var bots = map[string]models.Player{}
+var botsMu sync.Mutex
+
+// turnsMu is held for reading while a bot plays its turn, a botworker that shuts down takes it to wait
+// for the turns being played. No turn is played once stopping is set.
+var turnsMu sync.RWMutex
+var stopping bool
+
+func init() {
+	pid = os.Getpid()
+	config.LoadConfig()
+	redisConData := config.Cfg.Redis
+	client, err := redisdb.NewRedisClient(redisConData.Addr, redisConData.User, redisConData.Password)
+	if err != nil {
+		log.Fatalf("[%s-Redis] Error initializing Redis client: %v\n", name, err)
+	}
+	redisClient = client
+}
+
+func main() {
+	defer func() {
+		if redisClient != nil {
+			redisClient.CloseRedisClient()
+		}
+	}()
+
+	// On SIGTERM the botworker stops reading the queue, finishes the turns being played and releases its
+	// bots, the other botworkers adopt them.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	log.Printf("[%s-%d] - Waiting for bot players...\n", name, pid)
+	var workers sync.WaitGroup
+	for _, process := range []func(context.Context){
+		processBotPlayers,
+		processBotLeases,
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			process(ctx)
+		}()
+	}
+	<-ctx.Done()
+	log.Printf("[%s-%d] - Shutting down, finishing the turns being played...\n", name, pid)
+	workers.Wait()
+	turnsMu.Lock()
+	stopping = true
+	turnsMu.Unlock()
+	releaseBots()
+	log.Printf("[%s-%d] - Shut down\n", name, pid)
+}
+
+// processBotPlayers picks up the bots the roomworker pairs with players. Each bot listens to its own
+// player channel, like the wsapi does for the players, and sends its moves to the game mailbox.
+func processBotPlayers(ctx context.Context) {
+	redisClient.ConsumePlayers(ctx, "bot_players", name, consumer, func(bot *models.Player) error {
+		// The bot is stored before the message is acknowledged, if this botworker stops now another one adopts it.
+		// A bot that could not be stored is dead-lettered instead, it can be replayed from there.
+		if err := redisClient.SaveBot(*bot); err != nil {
+			return err
+		}
+		log.Printf("[%s-%d] - (Process Bot Players) - Bot %s playing at %s difficulty\n", name, pid, bot.ID, engine.GetDifficulty(bot.BotDifficulty).Name)
+		claimBot(*bot)
+		return nil
+	})
+}
+
+// processBotLeases extends the leases of the bots run here and adopts the bots left without a botworker,
+// e.g. after a restart.
+func processBotLeases(ctx context.Context) {
+	ticker := time.NewTicker(botLeaseRenewal)
+	defer ticker.Stop()
+	for {
+		renewBotLeases()
+		adoptBots()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func renewBotLeases() {
+	botsMu.Lock()
+	running := make([]models.Player, 0, len(bots))
+	for _, bot := range bots {
+		running = append(running, bot)
+	}
+	botsMu.Unlock()
+
+	for _, bot := range running {
+		claimed, err := redisClient.ClaimBot(bot.ID, consumer, botLease)
+		if err != nil {
+			log.Printf("[%s-%d] - (Renew Bot Leases) - %v\n", name, pid, err)
+			continue
+		}
+		if !claimed {
+			// The lease expired and another botworker adopted the bot.
+			log.Printf("[%s-%d] - (Renew Bot Leases) - Lost the lease of bot %s\n", name, pid, bot.ID)
+			dropBot(bot)
+		}
+	}
+}
+
+// adoptBots starts the stored bots that no botworker runs. The bot may have missed its turn, or the end of
+// its game, while it had no botworker.
+func adoptBots() {
+	stored, err := redisClient.GetBots()
+	if err != nil {
+		log.Printf("[%s-%d] - (Adopt Bots) - %v\n", name, pid, err)
+		return
+	}
+	for _, bot := range stored {
+		botsMu.Lock()
+		_, running := bots[bot.ID]
+		botsMu.Unlock()
+		if running || !claimBot(bot) {
+			continue
+		}
+		log.Printf("[%s-%d] - (Adopt Bots) - Adopted bot %s\n", name, pid, bot.ID)
+		player, err := redisClient.GetPlayer(bot.ID)
+		if err != nil {
+			if exists, existsErr := redisClient.PlayerExists(bot.ID); existsErr == nil && !exists {
+				stopBot(bot)
+			}
+			continue
+		}
+		if player.GameID != "" {
+			playTurn(bot, rand.New(rand.NewSource(time.Now().UnixNano())))
+			continue
+		}
+		if _, err := redisClient.GetRoomByID(player.RoomID); player.RoomID == "" || err != nil {
+			stopBot(bot)
+		}
+	}
+}
+
+// claimBot takes the lease of the bot and starts it, it returns false when another botworker runs it.
+func claimBot(bot models.Player) bool {
+	claimed, err := redisClient.ClaimBot(bot.ID, consumer, botLease)
+	if err != nil {
+		log.Printf("[%s-%d] - (Claim Bot) - %v\n", name, pid, err)
+		return false
+	}
+	if claimed {
+		startBot(bot)
+	}
+	return claimed
+}
+
+func startBot(bot models.Player) {
+	botsMu.Lock()
+	bots[bot.ID] = bot
+	botsMu.Unlock()
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	redisClient.SubscribePlayerChannel(bot, func(data string) {
+		handleBotMessage(bot, rng, data)
+	})
+}
+
+// releaseBots stops running the bots here and gives up their leases, so another botworker can adopt
+// them without waiting for the leases to expire.
+func releaseBots() {
+	botsMu.Lock()
+	running := make([]models.Player, 0, len(bots))
+	for _, bot := range bots {
+		running = append(running, bot)
+	}
+	botsMu.Unlock()
+
+	for _, bot := range running {
+		dropBot(bot)
+		if err := redisClient.ReleaseBot(bot.ID, consumer); err != nil {
+			log.Printf("[%s-%d] - (Release Bots) - %v\n", name, pid, err)
+		}
+	}
+}
+
+// dropBot stops running the bot here, without removing it.
+func dropBot(bot models.Player) {
+	redisClient.UnsubscribePlayerChannel(bot)
+	botsMu.Lock()
+	delete(bots, bot.ID)
+	botsMu.Unlock()
+}
+
+// stopBot removes the bot once its game or room is over.
+func stopBot(bot models.Player) {
+	dropBot(bot)
+	if err := redisClient.RemovePlayer(bot.ID); err != nil {
+		log.Printf("[%s-%d] - (Stop Bot) - Failed to remove bot %s: %v\n", name, pid, bot.ID, err)
+	}
+	if err := redisClient.RemoveBot(bot.ID); err != nil {
+		log.Printf("[%s-%d] - (Stop Bot) - %v\n", name, pid, err)
+	}
+}
+
+func handleBotMessage(bot models.Player, rng *rand.Rand, data string) {
+	var message messages.Message[json.RawMessage]
+	if err := json.Unmarshal([]byte(data), &message); err != nil {
+		return // Not a client message, e.g. disconnect requests.
+	}
+	switch message.Command {
+	case "game_start", "turn_switch":
+		turnsMu.RLock()
+		defer turnsMu.RUnlock()
+		if !stopping {
+			playTurn(bot, rng)
+		}
+	case "invalid_move":
+		log.Printf("[%s-%d] - (Handle Bot Message) - Bot %s move was rejected: %s\n", name, pid, bot.ID, string(message.Value))
+	case "game_over", "opponent_left_room":
+		stopBot(bot)
+	}
+}
+
+// playTurn plays the bot move when it is the bot turn, the whole capture sequence is sent at once.
+func playTurn(bot models.Player, rng *rand.Rand) {
+	player, err := redisClient.GetPlayer(bot.ID)
+	if err != nil {
+		log.Printf("[%s-%d] - (Play Turn) - Failed to get bot player: %v\n", name, pid, err)
+		return
+	}
+	game, err := redisClient.GetGame(player.GameID)
+	if err != nil {
+		log.Printf("[%s-%d] - (Play Turn) - Failed to get game: %v\n", name, pid, err)
+		return
+	}
+	if game.CurrentPlayerID != bot.ID {
+		return
+	}
+	time.Sleep(time.Duration(config.Cfg.Services["botworker"].BotMoveDelay) * time.Millisecond)
+
+	move, err := chooseMove(game, bot, rng)
+	if err != nil {
+		log.Printf("[%s-%d] - (Play Turn) - %v, position: %s\n", name, pid, err, game.FEN())
+		return
+	}
+	move.ReceivedAt = time.Now()
+	if err := redisClient.SendGameCommand(models.GameCommandMove, game.ID, move); err != nil {
+		log.Printf("[%s-%d] - (Play Turn) - Error pushing move: %v\n", name, pid, err)
+	}
+}
+
+// chooseMove searches the game position at the bot difficulty and returns the move with its full path.
+func chooseMove(game *models.Game, bot models.Player, rng *rand.Rand) (models.Move, error) {
+	side := models.SideBlack
+	for _, gamePlayer := range game.Players {
+		if gamePlayer.ID == bot.ID && gamePlayer.Color == "w" {
+			side = models.SideWhite
+		}
+	}
+	pos := game.Board.Position()
+	positionMove, ok := engine.ChooseMove(pos, side, engine.GetDifficulty(bot.BotDifficulty), rng)
+	if !ok {
+		return models.Move{}, fmt.Errorf("(chooseMove) - bot %s has no legal moves", bot.ID)
+	}
+	path := make([]string, len(positionMove.Path))
+	for i, sq := range positionMove.Path {
+		path[i] = pos.SquareName(sq)
+	}
+	piece := game.Board.Grid[path[0]]
+	if piece == nil {
+		return models.Move{}, fmt.Errorf("(chooseMove) - no piece on %s", path[0])
+	}
+	return models.Move{
+		PlayerID:  bot.ID,
+		PieceID:   piece.PieceID,
+		From:      path[0],
+		To:        path[len(path)-1],
+		IsCapture: positionMove.IsCapture(),
+		Path:      path,
+	}, nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/Lavizord/checkers-server/config"
@@ -34,7 +37,16 @@ func main() {
 			redisClient.CloseRedisClient()
 		}
 	}()
-	for range ticker.C {
+	// On SIGTERM the broadcastworker stops after the broadcast it is sending.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[BroadcastWorker-%d] - Shut down\n", pid)
+			return
+		case <-ticker.C:
+		}
 		msg, _ := messages.GenerateGameInfoMessageBytes(redisClient)
 		// Publish the message
 		err := redisClient.Publish("game_info", msg)
@@ -14,12 +14,32 @@ import (
 	},
 	"services": {
 		"wsapi": { "ports": [8080, 8081, 8082] },
+		"restapi": {
+			"ports": [80],
+			"max_analyses": 2,					// Game analyses run at the same time, the other requests get a 503.
+			"admin_token": "..."				// Bearer token of the dead letter routes, ADMIN_TOKEN overrides it. Unset disables them.
+		},
 		"pstatusworker": {},
-		"roomworker": {},
+		"roomworker": {
+			"timer": 1,
+			"bot_wait": 30,						// Seconds a player of a practice game waits in the queue before playing a bot, 0 disables it.
+			"bot_difficulty": "medium"			// Difficulty of the bots of the practice games, see engine.Difficulties.
+		},
+		"botworker": {
+			"bot_move_delay": 800				// Milliseconds the bots wait before moving, so their moves can be followed.
+		},
 		"gameworker": {
 			"timer": 15,
-			"timer_settings": "reset", 			// Options: "reset" or "cumulative"
-			"pieces_in_match": 10 				// Number of pieces in the match
+			"timer_settings": "reset", 			// Options: "reset" or "cumulative", clock of the games without a time control preset.
+			"bet_time_controls": {"0.5": "bullet"},	// Time control preset of each bet, see models.TimeControls. Operator presets come first.
+			"pieces_in_match": 10, 				// Number of pieces in the match
+			"draw_repetitions": 3,				// Same position repeated this many times is a draw, 0 disables it.
+			"draw_moves_without_progress": 50,	// Moves without a capture or a man move before a draw, 0 disables it.
+			"draw_king_vs_king": true,			// A single king against a single king is a draw.
+			"draw_offers_per_game": 3,			// Draw offers each player can make in a game, 0 means no limit.
+			"start_fen": "",						// Optional test position practice games start from, e.g. models.EndGameTestFEN.
+			"hints_per_game": 3,				// Hints each player can ask for in a practice game, 0 means no limit.
+			"hint_cooldown": 10					// Seconds between two hints of a player.
 		}
 	}
 	}
@@ -49,6 +69,25 @@ type Config struct {
 		Timer         int    `json:"timer,omitempty"`
 		TimerSetting  string `json:"timer_setting,omitempty"`
 		PiecesInMatch int    `json:"pieces_in_match,omitempty"`
+
+		BetTimeControls map[string]string `json:"bet_time_controls,omitempty"`
+
+		DrawRepetitions          int  `json:"draw_repetitions,omitempty"`
+		DrawMovesWithoutProgress int  `json:"draw_moves_without_progress,omitempty"`
+		DrawKingVsKing           bool `json:"draw_king_vs_king,omitempty"`
+		DrawOffersPerGame        int  `json:"draw_offers_per_game,omitempty"`
+
+		StartFEN string `json:"start_fen,omitempty"`
+
+		BotWait       int    `json:"bot_wait,omitempty"`
+		BotDifficulty string `json:"bot_difficulty,omitempty"`
+		BotMoveDelay  int    `json:"bot_move_delay,omitempty"`
+
+		HintsPerGame int `json:"hints_per_game,omitempty"`
+		HintCooldown int `json:"hint_cooldown,omitempty"`
+
+		MaxAnalyses int    `json:"max_analyses,omitempty"`
+		AdminToken  string `json:"admin_token,omitempty"`
 	} `json:"services"`
 }
 
@@ -13,7 +13,9 @@ BEGIN
             Active BOOLEAN DEFAULT TRUE, 
             GameBaseUrl VARCHAR(255),    
             OperatorWalletBaseUrl VARCHAR(255),
-            WinFactor DECIMAL(5,4)                      
+            WinFactor DECIMAL(5,4),
+            Variant VARCHAR(50) DEFAULT 'classic',
+            TimeControl VARCHAR(50)
         );
 
         INSERT INTO operators (OperatorName, OperatorGameName, GameName, GameBaseUrl, OperatorWalletBaseUrl, WinFactor)
@@ -36,6 +38,9 @@ BEGIN
     END IF;
 END $$;
 
+ALTER TABLE operators ADD COLUMN IF NOT EXISTS Variant VARCHAR(50) DEFAULT 'classic';
+ALTER TABLE operators ADD COLUMN IF NOT EXISTS TimeControl VARCHAR(50);
+
 CREATE TABLE IF NOT EXISTS sessions (
     SessionId UUID PRIMARY KEY,  
     Token VARCHAR(255),         
@@ -62,13 +67,27 @@ CREATE TABLE IF NOT EXISTS games (
     Winner UUID ,                     
     WinFactor DECIMAL(5,4), 
     GameOverReason VARCHAR(50),
-    GamePlayers JSONB DEFAULT '[]'  
+    GamePlayers JSONB DEFAULT '[]',
+    Variant VARCHAR(50) DEFAULT 'classic',
+    IsPractice BOOLEAN DEFAULT FALSE,
+    TimeControl JSONB,
+    DrawRule VARCHAR(50),
+    StartFEN TEXT
 );
 
+ALTER TABLE games ADD COLUMN IF NOT EXISTS Variant VARCHAR(50) DEFAULT 'classic';
+ALTER TABLE games ADD COLUMN IF NOT EXISTS IsPractice BOOLEAN DEFAULT FALSE;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS TimeControl JSONB;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS DrawRule VARCHAR(50);
+ALTER TABLE games ADD COLUMN IF NOT EXISTS StartFEN TEXT;
+
+CREATE OR REPLACE VIEW money_games AS
+    SELECT * FROM games WHERE NOT IsPractice;
+
 CREATE TABLE IF NOT EXISTS transactions (
     TransactionID UUID PRIMARY KEY,    
     SessionID UUID ,                   
-    Type VARCHAR(50)  CHECK (Type IN ('bet', 'win')), 
+    Type VARCHAR(50)  CHECK (Type IN ('bet', 'win', 'refund')), 
     Amount INTEGER  CHECK (Amount >= 0),  
     Currency VARCHAR(10) ,  
     Platform VARCHAR(100) , 
@@ -79,4 +98,7 @@ CREATE TABLE IF NOT EXISTS transactions (
     Description VARCHAR(600),           
     RoundID UUID,                       
     Timestamp TIMESTAMP  DEFAULT CURRENT_TIMESTAMP  
-);
\ No newline at end of file
+);
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_type_check CHECK (Type IN ('bet', 'win', 'refund'));
\ No newline at end of file
@@ -15,7 +15,9 @@ BEGIN
             Active BOOLEAN DEFAULT TRUE,        -- Whether the operator is active or not, default to TRUE (1)
             GameBaseUrl VARCHAR(255),             -- gamelaunch base url
             OperatorWalletBaseUrl VARCHAR(255),
-            WinFactor DECIMAL(5,4)                      
+            WinFactor DECIMAL(5,4),
+            Variant VARCHAR(50) DEFAULT 'classic',  -- Checkers rules of the operator games: classic, english, brazilian, international, russian or italian
+            TimeControl VARCHAR(50)                 -- Time control preset of the operator games, e.g. blitz, NULL uses the bet or default clock
         );
 
         -- Insert a row into the table after creating it
@@ -39,6 +41,9 @@ BEGIN
     END IF;
 END $$;
 
+ALTER TABLE operators ADD COLUMN IF NOT EXISTS Variant VARCHAR(50) DEFAULT 'classic';
+ALTER TABLE operators ADD COLUMN IF NOT EXISTS TimeControl VARCHAR(50);
+
 CREATE TABLE IF NOT EXISTS sessions (
     SessionId UUID PRIMARY KEY,  -- Unique session ID
     Token VARCHAR(255),          -- Session token
@@ -68,13 +73,28 @@ CREATE TABLE IF NOT EXISTS games (
     Winner UUID ,                     
     WinFactor DECIMAL(5,4), 
     GameOverReason VARCHAR(50),
-    GamePlayers JSONB DEFAULT '[]'  
+    GamePlayers JSONB DEFAULT '[]',
+    Variant VARCHAR(50) DEFAULT 'classic',  -- Checkers rules the game was played with
+    IsPractice BOOLEAN DEFAULT FALSE,       -- Practice games are free, they have no transactions
+    TimeControl JSONB,                      -- Clock the game was played with
+    DrawRule VARCHAR(50),                   -- Rule or agreement that ended the game in a draw, NULL when it was not a draw
+    StartFEN TEXT                           -- Position the game started from, NULL for the initial position of the variant
 );
 
+ALTER TABLE games ADD COLUMN IF NOT EXISTS Variant VARCHAR(50) DEFAULT 'classic';
+ALTER TABLE games ADD COLUMN IF NOT EXISTS IsPractice BOOLEAN DEFAULT FALSE;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS TimeControl JSONB;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS DrawRule VARCHAR(50);
+ALTER TABLE games ADD COLUMN IF NOT EXISTS StartFEN TEXT;
+
+-- Games played for money, financial reports must use this view instead of the games table.
+CREATE OR REPLACE VIEW money_games AS
+    SELECT * FROM games WHERE NOT IsPractice;
+
 CREATE TABLE IF NOT EXISTS transactions (
     TransactionID UUID PRIMARY KEY,    
     SessionID UUID ,                   
-    Type VARCHAR(50)  CHECK (Type IN ('bet', 'win')), 
+    Type VARCHAR(50)  CHECK (Type IN ('bet', 'win', 'refund')), 
     Amount INTEGER  CHECK (Amount >= 0),  
     Currency VARCHAR(10) ,  
     Platform VARCHAR(100) , -- Platform name
@@ -87,6 +107,10 @@ CREATE TABLE IF NOT EXISTS transactions (
     Timestamp TIMESTAMP  DEFAULT CURRENT_TIMESTAMP  -- Timestamp in UTC
 );
 
+-- Tables created before refunds keep the old ('bet', 'win') check, replace it.
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_type_check CHECK (Type IN ('bet', 'win', 'refund'));
+
 CREATE TABLE IF NOT EXISTS users (
     Id UUID PRIMARY KEY,
     Email VARCHAR(255) UNIQUE NOT NULL,
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"slices"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+// Search limits of the in-game hints and of the post-game analysis, that searches every turn of a game.
+const (
+	HintDepth       = 8
+	HintMaxTime     = time.Second
+	AnalysisDepth   = 6
+	AnalysisMaxTime = 300 * time.Millisecond
+)
+
+// Hint is a legal move in board squares, with its score for the side that plays it.
+type Hint struct {
+	Path     []string `json:"path"`     // e.g. ["C3", "E5", "G3"]
+	Captures []string `json:"captures"` // e.g. ["D4", "F4"]
+	Score    int      `json:"score"`
+}
+
+// BoardAnalysis is the evaluation of a board for the side to move.
+type BoardAnalysis struct {
+	FEN       string `json:"fen"`
+	Score     int    `json:"score"` // Score of the best move, positive is better for the side to move.
+	Depth     int    `json:"depth"`
+	BestMoves []Hint `json:"best_moves"` // Best first, every legal move.
+}
+
+// TurnAnalysis compares a played turn with the best move of the position.
+type TurnAnalysis struct {
+	Turn        int      `json:"turn"`
+	PlayerID    string   `json:"player_id"`
+	FEN         string   `json:"fen"`
+	Played      []string `json:"played"`
+	PlayedScore int      `json:"played_score"`
+	Best        Hint     `json:"best"`
+	Loss        int      `json:"loss"` // Score lost against the best move, 0 when the best move was played.
+	Depth       int      `json:"depth"`
+	ThinkTime   int64    `json:"think_time_ms,omitempty"` // Time the player took for the turn.
+}
+
+// AnalyzeBoard scores every legal move of side on the board.
+func AnalyzeBoard(board *models.Board, side models.Side, depth int, maxTime time.Duration) BoardAnalysis {
+	analysis := AnalyzePosition(board.Position(), side, depth, maxTime)
+	analysis.FEN = board.FEN(side)
+	return analysis
+}
+
+// AnalyzePosition is AnalyzeBoard for a position of the rules engine, the FEN is left empty.
+func AnalyzePosition(pos models.Position, side models.Side, depth int, maxTime time.Duration) BoardAnalysis {
+	return newBoardAnalysis(pos, Analyze(pos, side, depth, maxTime))
+}
+
+// AnalyzeContinuation scores the ways the piece on square can go on capturing, in the middle of a
+// capture sequence played hop by hop.
+func AnalyzeContinuation(board *models.Board, side models.Side, square string, depth int, maxTime time.Duration) BoardAnalysis {
+	pos := board.Position()
+	sq, ok := pos.SquareIndex(square)
+	if !ok {
+		return BoardAnalysis{FEN: board.FEN(side), BestMoves: []Hint{}}
+	}
+	analysis := newBoardAnalysis(pos, AnalyzeCaptures(pos, side, sq, depth, maxTime))
+	analysis.FEN = board.FEN(side)
+	return analysis
+}
+
+func newBoardAnalysis(pos models.Position, result Result) BoardAnalysis {
+	analysis := BoardAnalysis{Depth: result.Depth, BestMoves: []Hint{}}
+	for _, m := range result.Moves {
+		analysis.BestMoves = append(analysis.BestMoves, newHint(pos, m))
+	}
+	if best, ok := result.Best(); ok {
+		analysis.Score = best.Score
+	}
+	return analysis
+}
+
+// AnalyzeTurns scores the move played in every turn against the best one.
+func AnalyzeTurns(turns []models.ReplayTurn, depth int, maxTime time.Duration) []TurnAnalysis {
+	analysis := make([]TurnAnalysis, 0, len(turns))
+	for i, turn := range turns {
+		result := Analyze(turn.Position, turn.Side, depth, maxTime)
+		best, ok := result.Best()
+		if !ok {
+			continue
+		}
+		ta := TurnAnalysis{
+			Turn:      i,
+			PlayerID:  turn.PlayerID,
+			FEN:       turn.FEN,
+			Played:    turn.Path,
+			Best:      newHint(turn.Position, best),
+			Depth:     result.Depth,
+			ThinkTime: turn.ThinkTime,
+		}
+		ta.PlayedScore = ta.Best.Score
+		for _, m := range result.Moves {
+			if slices.Equal(newHint(turn.Position, m).Path, turn.Path) {
+				ta.PlayedScore = m.Score
+				break
+			}
+		}
+		ta.Loss = ta.Best.Score - ta.PlayedScore
+		analysis = append(analysis, ta)
+	}
+	return analysis
+}
+
+func newHint(pos models.Position, m ScoredMove) Hint {
+	hint := Hint{Score: m.Score, Captures: []string{}}
+	for _, sq := range m.Move.Path {
+		hint.Path = append(hint.Path, pos.SquareName(sq))
+	}
+	for _, sq := range m.Move.Captures {
+		hint.Captures = append(hint.Captures, pos.SquareName(sq))
+	}
+	return hint
+}
@@ -0,0 +1,47 @@
+package engine
+
+import "time"
+
+// Difficulty sets how strong a bot plays.
+type Difficulty struct {
+	Name       string        `json:"name"`
+	Depth      int           `json:"depth"`      // Plies searched.
+	Randomness int           `json:"randomness"` // Moves scoring within this margin of the best one are picked at random, 0 always plays the best move.
+	MaxTime    time.Duration `json:"max_time"`   // Search time limit for a move, the deepest search finished in time is played.
+}
+
+const DefaultDifficulty = "medium"
+
+var Difficulties = map[string]Difficulty{
+	"easy": {
+		Name:       "easy",
+		Depth:      2,
+		Randomness: 150, // Misses more than a man.
+		MaxTime:    time.Second,
+	},
+	"medium": {
+		Name:       "medium",
+		Depth:      4,
+		Randomness: 40,
+		MaxTime:    2 * time.Second,
+	},
+	"hard": {
+		Name:       "hard",
+		Depth:      8,
+		Randomness: 0,
+		MaxTime:    3 * time.Second,
+	},
+}
+
+// GetDifficulty returns the difficulty by name, unknown or empty names fall back to the default one.
+func GetDifficulty(name string) Difficulty {
+	if difficulty, ok := Difficulties[name]; ok {
+		return difficulty
+	}
+	return Difficulties[DefaultDifficulty]
+}
+
+func IsValidDifficulty(name string) bool {
+	_, ok := Difficulties[name]
+	return ok
+}
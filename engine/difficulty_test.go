@@ -0,0 +1,51 @@
+package engine
+
+import "testing"
+
+func TestGetDifficulty(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantName  string
+		wantDepth int
+		wantValid bool
+	}{
+		{name: "easy", wantName: "easy", wantDepth: 2, wantValid: true},
+		{name: "medium", wantName: "medium", wantDepth: 4, wantValid: true},
+		{name: "hard", wantName: "hard", wantDepth: 8, wantValid: true},
+		{name: "", wantName: DefaultDifficulty, wantDepth: 4},
+		{name: "grandmaster", wantName: DefaultDifficulty, wantDepth: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			difficulty := GetDifficulty(tt.name)
+			if difficulty.Name != tt.wantName || difficulty.Depth != tt.wantDepth {
+				t.Errorf("GetDifficulty(%q) = %s at depth %d, want %s at depth %d", tt.name, difficulty.Name, difficulty.Depth, tt.wantName, tt.wantDepth)
+			}
+			if got := IsValidDifficulty(tt.name); got != tt.wantValid {
+				t.Errorf("IsValidDifficulty(%q) = %t, want %t", tt.name, got, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestDifficultiesSearchTheirDepth(t *testing.T) {
+	pos, side := testPosition(t, "B:W22,23:B9,10", "classic")
+	for name, difficulty := range Difficulties {
+		t.Run(name, func(t *testing.T) {
+			result := Analyze(pos, side, difficulty.Depth, difficulty.MaxTime)
+			if result.Depth != difficulty.Depth {
+				t.Errorf("Depth = %d, want %d", result.Depth, difficulty.Depth)
+			}
+		})
+	}
+}
+
+func TestDifficultiesGetStronger(t *testing.T) {
+	easy, medium, hard := GetDifficulty("easy"), GetDifficulty("medium"), GetDifficulty("hard")
+	if !(easy.Depth < medium.Depth && medium.Depth < hard.Depth) {
+		t.Errorf("depths %d, %d, %d, want them growing from easy to hard", easy.Depth, medium.Depth, hard.Depth)
+	}
+	if !(easy.Randomness > medium.Randomness && medium.Randomness > hard.Randomness) {
+		t.Errorf("randomness %d, %d, %d, want it shrinking from easy to hard", easy.Randomness, medium.Randomness, hard.Randomness)
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"math/bits"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+const (
+	ManValue     = 100
+	KingValue    = 300
+	AdvanceValue = 3 // Per row a man has advanced, men close to promotion are worth more.
+	BackRowValue = 8 // Men still on their first row keep the opponent from promoting.
+
+	// WinScore is the score of a won position, wins found sooner score higher.
+	WinScore = 100000
+)
+
+// Evaluate scores the position from the point of view of side, positive is better for side.
+func Evaluate(pos models.Position, side models.Side) int {
+	score := material(pos, models.SideBlack) - material(pos, models.SideWhite)
+	if side == models.SideWhite {
+		return -score
+	}
+	return score
+}
+
+func material(pos models.Position, side models.Side) int {
+	own := pos.Black
+	if side == models.SideWhite {
+		own = pos.White
+	}
+	lastRow := pos.Rules.BoardSize - 1
+	score := 0
+	for ; own != 0; own &= own - 1 {
+		sq := bits.TrailingZeros64(own)
+		if pos.Kings&(uint64(1)<<sq) != 0 {
+			score += KingValue
+			continue
+		}
+		advanced := pos.Row(sq)
+		if side == models.SideWhite {
+			advanced = lastRow - advanced
+		}
+		score += ManValue + AdvanceValue*advanced
+		if advanced == 0 {
+			score += BackRowValue
+		}
+	}
+	return score
+}
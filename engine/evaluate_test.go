@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	pos, _ := testPosition(t, "", "classic")
+	if got := Evaluate(pos, models.SideBlack); got != 0 {
+		t.Errorf("Evaluate() of the initial position = %d, want 0", got)
+	}
+
+	pos, _ = testPosition(t, "B:W22:B9,10", "classic")
+	black, white := Evaluate(pos, models.SideBlack), Evaluate(pos, models.SideWhite)
+	if black <= 0 || white != -black {
+		t.Errorf("Evaluate() = %d for black and %d for white, want black ahead by a man", black, white)
+	}
+
+	man, _ := testPosition(t, "B:W22:B14", "classic")
+	king, _ := testPosition(t, "B:W22:BK14", "classic")
+	if Evaluate(king, models.SideBlack) <= Evaluate(man, models.SideBlack) {
+		t.Errorf("a king scores %d, not more than the %d of a man", Evaluate(king, models.SideBlack), Evaluate(man, models.SideBlack))
+	}
+	advanced, _ := testPosition(t, "B:W22:B18", "classic")
+	if Evaluate(advanced, models.SideBlack) <= Evaluate(man, models.SideBlack) {
+		t.Errorf("a man closer to promotion scores %d, not more than %d", Evaluate(advanced, models.SideBlack), Evaluate(man, models.SideBlack))
+	}
+}
@@ -0,0 +1,124 @@
+// Package engine searches the checkers positions of the rules engine, it picks the bot moves.
+package engine
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+// ScoredMove is a legal move with the score of the position it leads to, for the side that plays it.
+type ScoredMove struct {
+	Move  models.PositionMove `json:"move"`
+	Score int                 `json:"score"`
+}
+
+// Result holds every legal move of the searched position, best first.
+type Result struct {
+	Moves []ScoredMove `json:"moves"`
+	Depth int          `json:"depth"` // Deepest search finished in time.
+	Nodes int          `json:"nodes"`
+}
+
+// Best returns the best move, and false when the side has no legal moves.
+func (r Result) Best() (ScoredMove, bool) {
+	if len(r.Moves) == 0 {
+		return ScoredMove{}, false
+	}
+	return r.Moves[0], true
+}
+
+type searcher struct {
+	deadline time.Time
+	nodes    int
+	aborted  bool
+}
+
+// Analyze scores every legal move of side with an alpha-beta search, deepening one ply at a time up
+// to depth. When maxTime runs out the scores of the deepest finished search are returned, 0 means no
+// time limit.
+func Analyze(pos models.Position, side models.Side, depth int, maxTime time.Duration) Result {
+	return analyzeMoves(pos, side, pos.LegalMoves(side), depth, maxTime)
+}
+
+// AnalyzeCaptures is Analyze for a capture sequence that must go on with the piece on sq.
+func AnalyzeCaptures(pos models.Position, side models.Side, sq int, depth int, maxTime time.Duration) Result {
+	return analyzeMoves(pos, side, pos.CapturesFrom(side, sq), depth, maxTime)
+}
+
+func analyzeMoves(pos models.Position, side models.Side, moves []models.PositionMove, depth int, maxTime time.Duration) Result {
+	s := &searcher{}
+	if maxTime > 0 {
+		s.deadline = time.Now().Add(maxTime)
+	}
+	var result Result
+	for _, move := range moves {
+		result.Moves = append(result.Moves, ScoredMove{Move: move})
+	}
+	if len(result.Moves) == 0 {
+		return result
+	}
+	for d := 1; d <= depth; d++ {
+		scored := make([]ScoredMove, len(result.Moves))
+		for i, m := range result.Moves {
+			next := pos.Apply(side, m.Move)
+			scored[i] = ScoredMove{Move: m.Move, Score: -s.alphaBeta(next, side.Opponent(), d-1, 1, -WinScore-1, WinScore+1)}
+		}
+		if s.aborted {
+			break
+		}
+		sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+		result.Moves, result.Depth = scored, d
+	}
+	result.Nodes = s.nodes
+	return result
+}
+
+// ChooseMove picks the move a bot of the difficulty plays, and false when the side has no legal moves.
+func ChooseMove(pos models.Position, side models.Side, difficulty Difficulty, rng *rand.Rand) (models.PositionMove, bool) {
+	moves := pos.LegalMoves(side)
+	if len(moves) <= 1 {
+		if len(moves) == 0 {
+			return models.PositionMove{}, false
+		}
+		return moves[0], true
+	}
+	result := Analyze(pos, side, difficulty.Depth, difficulty.MaxTime)
+	best, _ := result.Best()
+	candidates := 1
+	for candidates < len(result.Moves) && best.Score-result.Moves[candidates].Score <= difficulty.Randomness {
+		candidates++
+	}
+	return result.Moves[rng.Intn(candidates)].Move, true
+}
+
+// alphaBeta returns the score of the position for side, searched depth plies deep. Pending captures
+// are searched past depth, a position is only evaluated when the side to move has no capture.
+func (s *searcher) alphaBeta(pos models.Position, side models.Side, depth, ply, alpha, beta int) int {
+	s.nodes++
+	if s.nodes&1023 == 0 && !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.aborted = true
+	}
+	if s.aborted {
+		return 0
+	}
+	moves := pos.LegalMoves(side)
+	if len(moves) == 0 {
+		return -WinScore + ply // The side to move lost, the sooner the worse.
+	}
+	if depth <= 0 && !moves[0].IsCapture() {
+		return Evaluate(pos, side)
+	}
+	for _, m := range moves {
+		score := -s.alphaBeta(pos.Apply(side, m), side.Opponent(), depth-1, ply+1, -beta, -alpha)
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return alpha
+}
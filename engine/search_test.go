@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+// testPosition parses the FEN into an engine position, "" is the initial position of the variant.
+func testPosition(t *testing.T, fen, variant string) (models.Position, models.Side) {
+	t.Helper()
+	if fen == "" {
+		return models.NewBoard("black", "white", variant).Position(), models.SideBlack
+	}
+	board, side, err := models.ParseFEN(fen, "black", "white", variant)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q): %v", fen, err)
+	}
+	return board.Position(), side
+}
+
+// moveNotation writes a move as its path, e.g. "C3-D4" or "C5xE7xG5".
+func moveNotation(pos models.Position, move models.PositionMove) string {
+	squares := make([]string, len(move.Path))
+	for i, sq := range move.Path {
+		squares[i] = pos.SquareName(sq)
+	}
+	if move.IsCapture() {
+		return strings.Join(squares, "x")
+	}
+	return strings.Join(squares, "-")
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name      string
+		variant   string
+		fen       string
+		depth     int
+		wantBest  string
+		wantScore int
+	}{
+		{name: "capture that wins the game", variant: "classic", fen: "B:W14:B10", depth: 1, wantBest: "C3xE5", wantScore: WinScore - 1},
+		{name: "multi-jump that wins the game", variant: "classic", fen: models.MultipleCaptureTestFEN, depth: 4, wantBest: "C5xE7xG5", wantScore: WinScore - 1},
+		{name: "move that does not give the man away", variant: "classic", fen: "B:W19:B10", depth: 2, wantBest: "C3-D2", wantScore: -3},
+		{name: "win found two plies deep", variant: "classic", fen: "B:W22:B9,10", depth: 2, wantBest: "C3-D4", wantScore: WinScore - 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, side := testPosition(t, tt.fen, tt.variant)
+			result := Analyze(pos, side, tt.depth, 0)
+			best, ok := result.Best()
+			if !ok {
+				t.Fatal("Best() found no move")
+			}
+			if got := moveNotation(pos, best.Move); got != tt.wantBest || best.Score != tt.wantScore {
+				t.Errorf("Best() = %s scoring %d, want %s scoring %d", got, best.Score, tt.wantBest, tt.wantScore)
+			}
+			if result.Depth != tt.depth {
+				t.Errorf("Depth = %d, want %d", result.Depth, tt.depth)
+			}
+		})
+	}
+}
+
+func TestAnalyzeDepthLimit(t *testing.T) {
+	// The win is two plies away, a search of one ply does not see it.
+	pos, side := testPosition(t, "B:W22:B9,10", "classic")
+	shallow, _ := Analyze(pos, side, 1, 0).Best()
+	deep, _ := Analyze(pos, side, 2, 0).Best()
+	if shallow.Score >= WinScore/2 || deep.Score != WinScore-3 {
+		t.Errorf("best scores %d at depth 1 and %d at depth 2, want the win only at depth 2", shallow.Score, deep.Score)
+	}
+}
+
+func TestAnalyzeScoresEveryMoveBestFirst(t *testing.T) {
+	pos, side := testPosition(t, "", "classic")
+	result := Analyze(pos, side, 4, 0)
+	if len(result.Moves) != len(pos.LegalMoves(side)) {
+		t.Fatalf("Analyze() scored %d moves, want %d", len(result.Moves), len(pos.LegalMoves(side)))
+	}
+	for i := 1; i < len(result.Moves); i++ {
+		if result.Moves[i].Score > result.Moves[i-1].Score {
+			t.Errorf("move %d scores %d, more than the %d of the move before it", i, result.Moves[i].Score, result.Moves[i-1].Score)
+		}
+	}
+	if result.Nodes == 0 {
+		t.Error("Nodes = 0, want the searched nodes")
+	}
+}
+
+func TestAnalyzeWithoutLegalMoves(t *testing.T) {
+	pos, side := testPosition(t, "B:W13,18:B9", "classic")
+	if best, ok := Analyze(pos, side, 4, 0).Best(); ok {
+		t.Errorf("Best() = %s, want no move", moveNotation(pos, best.Move))
+	}
+}
+
+func TestAnalyzeStopsAtTheTimeLimit(t *testing.T) {
+	pos, side := testPosition(t, "", "classic")
+	start := time.Now()
+	result := Analyze(pos, side, 30, 10*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Analyze() took %v with a 10ms limit", elapsed)
+	}
+	if result.Depth < 1 || result.Depth >= 30 {
+		t.Errorf("Depth = %d, want the deepest search finished in time", result.Depth)
+	}
+	if len(result.Moves) != len(pos.LegalMoves(side)) {
+		t.Errorf("Analyze() scored %d moves, want %d", len(result.Moves), len(pos.LegalMoves(side)))
+	}
+}
+
+func TestAnalyzeCaptures(t *testing.T) {
+	// Black C5 has captured on D6 and goes on from E7.
+	pos, side := testPosition(t, "B:W23:B1,2,4,5,6,20", "classic")
+	sq, _ := pos.SquareIndex("E7")
+	result := AnalyzeCaptures(pos, side, sq, 2, 0)
+	if len(result.Moves) != 1 || moveNotation(pos, result.Moves[0].Move) != "E7xG5" {
+		t.Errorf("AnalyzeCaptures() = %v, want only E7xG5", result.Moves)
+	}
+}
+
+func TestChooseMove(t *testing.T) {
+	tests := []struct {
+		name       string
+		fen        string
+		difficulty string
+		want       string // Empty when the side has no legal moves.
+	}{
+		{name: "only move", fen: "B:W14:B4,10", difficulty: "easy", want: "C3xE5"},
+		{name: "no legal moves", fen: "B:W13,18:B9", difficulty: "hard"},
+		{name: "hard plays the best move", fen: "B:W22:B9,10", difficulty: "hard", want: "C3-D4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, side := testPosition(t, tt.fen, "classic")
+			for seed := int64(0); seed < 10; seed++ {
+				move, ok := ChooseMove(pos, side, GetDifficulty(tt.difficulty), rand.New(rand.NewSource(seed)))
+				if ok != (tt.want != "") {
+					t.Fatalf("ChooseMove() found a move = %t, want %t", ok, tt.want != "")
+				}
+				if ok && moveNotation(pos, move) != tt.want {
+					t.Errorf("seed %d: ChooseMove() = %s, want %s", seed, moveNotation(pos, move), tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestChooseMoveStaysWithinTheRandomness(t *testing.T) {
+	pos, side := testPosition(t, "B:W21,22,23,24,26:B9,10,11,12,14", "classic")
+	for name, difficulty := range Difficulties {
+		t.Run(name, func(t *testing.T) {
+			difficulty.MaxTime = 0 // Same depth as the analysis below.
+			result := Analyze(pos, side, difficulty.Depth, 0)
+			best, _ := result.Best()
+			scores := map[string]int{}
+			for _, m := range result.Moves {
+				scores[moveNotation(pos, m.Move)] = m.Score
+			}
+			for seed := int64(0); seed < 20; seed++ {
+				move, _ := ChooseMove(pos, side, difficulty, rand.New(rand.NewSource(seed)))
+				if loss := best.Score - scores[moveNotation(pos, move)]; loss > difficulty.Randomness {
+					t.Errorf("seed %d: ChooseMove() = %s, %d under the best move, want at most %d", seed, moveNotation(pos, move), loss, difficulty.Randomness)
+				}
+			}
+		})
+	}
+}
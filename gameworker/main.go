@@ -3,12 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Lavizord/checkers-server/config"
+	"github.com/Lavizord/checkers-server/engine"
 	"github.com/Lavizord/checkers-server/interfaces"
 	"github.com/Lavizord/checkers-server/messages"
 	"github.com/Lavizord/checkers-server/models"
@@ -20,6 +25,23 @@ var pid int
 var redisClient *redisdb.RedisClient
 var postgresClient *postgrescli.PostgresCli
 var name = "GameWorker"
+var consumer = redisdb.ConsumerName()
+
+// hints tracks the hint searches running, a gameworker that shuts down answers them first.
+var hints sync.WaitGroup
+
+// gameEnds tracks the game over payouts and clean ups running, a gameworker that shuts down finishes them first.
+var gameEnds sync.WaitGroup
+
+// gameCommandLease is how long a gameworker keeps a game mailbox without extending it. The lease is
+// extended between commands, and every gameCommandLease/3 while a command runs.
+const gameCommandLease = 10 * time.Second
+
+// Errors of the game updates that leave the game as it is.
+var (
+	errGameOver     = errors.New("the game is over")
+	errClockRunning = errors.New("the clock is still running")
+)
 
 func init() {
 	pid = os.Getpid()
@@ -52,38 +74,52 @@ func main() {
 		}
 	}()
 
+	// On SIGTERM the gameworker stops reading the queues, finishes the messages it is handling and gives
+	// its game mailboxes to the other gameworkers. The game clocks are kept in Redis, any gameworker fires them.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
 	log.Printf("[%s-%d] - Waiting for Game messages...\n", name, pid)
-	go processGameCreation()
-	go processGameMoves()
-	go processLeaveGame()
-	go processDisconnectFromGame()
-	go processReconnectFromGame()
-	select {}
+	var workers sync.WaitGroup
+	for _, process := range []func(context.Context){
+		processGameCreation,
+		processHintRequests,
+		processGameClocks,
+		processGameCommands,
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			process(ctx)
+		}()
+	}
+	<-ctx.Done()
+	log.Printf("[%s-%d] - Shutting down, finishing the messages being handled...\n", name, pid)
+	workers.Wait()
+	hints.Wait()
+	gameEnds.Wait()
+	log.Printf("[%s-%d] - Shut down\n", name, pid)
 }
 
-func processGameCreation() {
-	for {
-		roomData, err := redisClient.BLPopGeneric("create_game", 0) // Block
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Game Creation) - Error retrieving room data: %v\n", name, pid, err)
-			continue
-		}
-
-		if len(roomData) < 2 {
-			log.Printf("[%s-%d] - (Process Game Creation) - Unexpected BLPop result: %+v\n", name, pid, roomData)
-			continue
-		}
+func processGameCreation(ctx context.Context) {
+	redisClient.ConsumeStream(ctx, "create_game", name, consumer, func(roomData string) error {
 		//log.Printf("[%s-%d] - (Process Game Creation) - create game!: %+v\n", name, pid, roomData)
 
 		var room models.Room
-		err = json.Unmarshal([]byte(roomData[1]), &room) // Extract second element
+		err := json.Unmarshal([]byte(roomData), &room)
 		if err != nil {
 			log.Printf("[%s-%d] - (Process Game Creation) - JSON Unmarshal Error: %v\n", name, pid, err)
-			continue
+			return err
 		}
 
 		player1, err := redisClient.GetPlayer(room.Player1.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get player 1: %w", err)
+		}
 		player2, err := redisClient.GetPlayer(room.Player2.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get player 2: %w", err)
+		}
 		game := room.NewGame()
 		// we need to update our players with a game ID.
 		player1.GameID = game.ID
@@ -98,355 +134,643 @@ func processGameCreation() {
 		err = redisClient.UpdatePlayer(player1)
 		err = redisClient.UpdatePlayer(player2)
 		err = redisClient.AddGame(game)
+		if err != nil {
+			return fmt.Errorf("failed to add game: %w", err)
+		}
 		redisClient.RemoveRoom(redisdb.GenerateRoomRedisKeyById(room.ID))
 		msg, err := messages.GenerateGameStartMessage(*game)
 
 		//log.Printf("[%s-%d] - (Process Game Creation) - Message to publish: %v\n", name, pid, string(msg))
 		BroadCastToGamePlayers(msg, *game)
-		go startTimer(game) // Start turn timer
-	}
+		startClock(game) // Start turn timer
+		return nil
+	})
 }
 
-func processGameMoves() {
-	for {
-		moveData, err := redisClient.BLPopGeneric("move_piece", 0) // Block
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Game Moves) - Error retrieving move data: %v\n", name, pid, err)
-			continue
+// handleMove plays the move and tells the players, the game is closed when the move ended it.
+func handleMove(gameID string, move models.Move) {
+	var outcome moveOutcome
+	var invalid error
+	game, err := redisClient.UpdateGameFunc(gameID, func(game *models.Game) error {
+		outcome, invalid = playMove(game, move, move.ReceivedAt)
+		return invalid
+	})
+	if invalid != nil {
+		rejected := move
+		if outcome.failedHop.PieceID != "" {
+			// The hops played before the failed one were not saved, the player gets the stored board.
+			rejected = outcome.failedHop
+			if stored, err := redisClient.GetGame(gameID); err == nil {
+				game = stored
+			}
 		}
-		//log.Printf("[%s-%d] - (Process Game Moves) - processing move DATA!: %+v\n", name, pid, moveData)
-
-		// We start by getting our move data, player, game and opponentPlayer.
-		var move models.Move
-		err = json.Unmarshal([]byte(moveData[1]), &move) // Extract second element
+		log.Printf("[%s-%d] - (Handle Move) - Invalid move detected: %v, position: %s\n", name, pid, invalid, game.FEN())
+		msginv, _ := messages.GenerateInvalidMoveMessage(*game, rejected, invalid)
+		redisClient.PublishToPlayerID(move.PlayerID, string(msginv))
+		return
+	}
+	if err != nil {
+		log.Printf("[%s-%d] - (Handle Move) - Failed to update game!: %v\n", name, pid, err)
+		return
+	}
+	for _, result := range outcome.hops {
+		msg, err := messages.GenerateMoveResultMessage(result)
 		if err != nil {
-			log.Printf("[%s-%d] - (Process Game Moves) - JSON Unmarshal Error: %v\n", name, pid, err)
-			continue
+			log.Printf("[%s-%d] - (Handle Move) - Failed to generate message: %v\n", name, pid, err)
 		}
-		player, err := redisClient.GetPlayer(move.PlayerID)
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Game Moves) - Failed to get player!: %v\n", name, pid, err)
-			continue
+		BroadCastToGamePlayers(msg, *game)
+	}
+	switch {
+	case game.IsOver():
+		closeGame(game, outcome.reason)
+	case outcome.turnChanged:
+		announceTurn(game, outcome.drawOfferExpired)
+	}
+}
+
+// processGameCommands runs the game mailboxes this gameworker owns. A mailbox is run by one gameworker
+// at a time and the commands of a game always go to the same mailbox, so the events of each game are
+// handled one at a time, in the order they arrived. The mailboxes of a gameworker that stops are taken
+// over by the others once its leases expire, with the commands it left pending. A gameworker that
+// shuts down releases its mailboxes once it finishes the commands it is running.
+func processGameCommands(ctx context.Context) {
+	var shards sync.WaitGroup
+	for shard := 0; shard < redisdb.GameCommandShards; shard++ {
+		shards.Add(1)
+		go func() {
+			defer shards.Done()
+			processGameCommandShard(ctx, shard)
+		}()
+	}
+	shards.Wait()
+}
+
+func processGameCommandShard(ctx context.Context, shard int) {
+	mailbox := redisClient.NewStreamConsumer(redisdb.GameCommandsQueue(shard), name, consumer)
+	owned := false
+	defer func() {
+		if err := redisClient.ReleaseGameCommandShard(shard, consumer); err != nil {
+			log.Printf("[%s-%d] - (Process Game Commands) - %v\n", name, pid, err)
 		}
-		game, err := redisClient.GetGame(player.GameID)
+	}()
+	for ctx.Err() == nil {
+		claimed, err := redisClient.ClaimGameCommandShard(shard, consumer, gameCommandLease)
 		if err != nil {
-			log.Printf("[%s-%d] - (Process Game Moves) - Failed to get game!: %v\n", name, pid, err)
-			continue
+			log.Printf("[%s-%d] - (Process Game Commands) - %v\n", name, pid, err)
 		}
-		if game.CurrentPlayerID != move.PlayerID {
-			log.Printf("[%s-%d] - (Process Game Moves) - Incorrect current player to process move!: %v\n", name, pid, moveData)
+		if !claimed {
+			owned = false
+			select {
+			case <-ctx.Done():
+			case <-time.After(gameCommandLease / 2):
+			}
 			continue
 		}
-
-		piece := game.Board.GetPieceByID(move.PieceID)
-		if !validMove(game, move, piece) {
-			log.Printf("Invalid move detected")
-			boardState, _ := messages.GenerateGameBoardState(*game)
-			msginv, _ := messages.NewMessage("invalid_move", boardState)
-			redisClient.PublishToPlayer(*player, string(msginv))
-			continue
+		if !owned {
+			if err := takeOverGameCommands(shard, mailbox); err != nil {
+				log.Printf("[%s-%d] - (Process Game Commands) - %v\n", name, pid, err)
+				continue
+			}
+			owned = true
 		}
-		// We move our piece.
-		if !game.MovePiece(move) {
-			log.Printf("[%s-%d] - (Process Game Moves) - Invalid Move!: %v\n", name, pid, moveData)
-			msginv, _ := messages.NewMessage("invalid_move", fmt.Sprintf("(Process Game Moves) - Invalid Move!: %v", moveData))
-			redisClient.PublishToPlayer(*player, string(msginv))
+		message, err := mailbox.Next(time.Second)
+		if err != nil {
+			log.Printf("[%s-%d] - (Process Game Commands) - %v\n", name, pid, err)
 			continue
 		}
-		game.UpdatePlayerPieces()
-		move.IsKinged = game.Board.WasPieceKinged(move.To, *piece)
-		if move.IsKinged {
-			piece.IsKinged = move.IsKinged
+		if message != nil {
+			owned = handleGameCommand(shard, mailbox, *message)
 		}
+	}
+}
 
-		// We send the message to the opponent player.
-		msg, err := messages.GenerateMoveMessage(move)
+// handleGameCommand runs the command while extending the lease of its mailbox, so a slow command does
+// not let another gameworker take the mailbox over. The command is only acknowledged when the mailbox is
+// still owned once it finishes, it returns false when the lease was lost.
+func handleGameCommand(shard int, mailbox *redisdb.StreamConsumer, message redisdb.StreamMessage) bool {
+	return mailbox.HandleOwned(message, func(payload string) error {
+		stop := renewGameCommandLease(shard)
+		defer stop()
+		return runGameCommand(payload)
+	}, func() bool {
+		claimed, err := redisClient.ClaimGameCommandShard(shard, consumer, gameCommandLease)
 		if err != nil {
-			log.Printf("[%s-%d] - (Process Game Moves) - Failed to generate message: %v\n", name, pid, string(msg))
+			log.Printf("[%s-%d] - (Process Game Commands) - %v\n", name, pid, err)
 		}
-		//log.Printf("[%s-%d] - (Process Game Moves) - Message to publish: %v\n", name, pid, string(msg))
-		opponent, _ := game.GetOpponentGamePlayer(move.PlayerID)
-		redisClient.PublishToGamePlayer(*opponent, string(msg))
-
-		// Since the move was validated and passed to the other player, its time to check for our end turn / end game conditions.
-		// This means we can add the move to our game.
-		game.Moves = append(game.Moves, move)
+		return claimed
+	})
+}
 
-		// We check for game Over
-		if game.CheckGameOver() {
-			handleGameEnd(game, "winner", move.PlayerID)
-			continue
-		}
-		// We check for a capture.
-		if !move.IsCapture {
-			handleTurnChange(game)
-			continue
-		}
-		if move.IsCapture && !game.Board.CanPieceCaptureNEW(move.To) {
-			handleTurnChange(game)
-			continue
-		}
-		if move.IsKinged {
-			handleTurnChange(game)
-			continue
+// renewGameCommandLease extends the lease of the mailbox until stop is called.
+func renewGameCommandLease(shard int) (stop func()) {
+	done := make(chan struct{})
+	var renewal sync.WaitGroup
+	renewal.Add(1)
+	go func() {
+		defer renewal.Done()
+		ticker := time.NewTicker(gameCommandLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				claimed, err := redisClient.ClaimGameCommandShard(shard, consumer, gameCommandLease)
+				if err != nil {
+					log.Printf("[%s-%d] - (Process Game Commands) - %v\n", name, pid, err)
+				} else if !claimed {
+					log.Printf("[%s-%d] - (Process Game Commands) - Lost the lease of game mailbox %d while running a command\n", name, pid, shard)
+				}
+			}
 		}
-		redisClient.UpdateGame(game) // we update our game at the end.
+	}()
+	return func() {
+		close(done)
+		renewal.Wait()
 	}
 }
 
-func processLeaveGame() {
+var errGameCommandsLost = errors.New("lost the lease of the game mailbox")
+
+// takeOverGameCommands runs the commands the last owner of the mailbox did not finish, before the new
+// ones, in the order they arrived.
+func takeOverGameCommands(shard int, mailbox *redisdb.StreamConsumer) error {
+	if err := mailbox.CreateGroup(); err != nil {
+		return err
+	}
 	for {
-		// Block until there is a game over message
-		playerData, err := redisClient.BLPop("leave_game", 0)
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Leave Game) - Error retrieving player data from leave game queue: %v\n", name, pid, err)
-			continue
-		}
-		//log.Printf("[%s-%d] - Processing the leave game: %+v\n", name, pid, playerData)
-		playerData, err = redisClient.GetPlayer(playerData.ID)
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Leave Game) - Error re-fetching player data.: %v\n", name, pid, err)
-			continue
+		pending, err := mailbox.Claim(0)
+		if err != nil || len(pending) == 0 {
+			return err
 		}
-		game, err := redisClient.GetGame(playerData.GameID)
-		if err != nil {
-			log.Printf("[%s-%d] - Error retrieving Game:%v\n", name, pid, err)
-			continue
+		for _, message := range pending {
+			if !handleGameCommand(shard, mailbox, message) {
+				return errGameCommandsLost
+			}
 		}
-		winnrID, _ := game.GetOpponentPlayerID(playerData.ID)
-		handleGameEnd(game, "player_left", winnrID)
 	}
 }
 
-func processDisconnectFromGame() {
-	for {
-		// Block until there is a game over message
-		// TODO: Update player date, its without a game.
-		playerData, err := redisClient.BLPop("disconnect_game", 0)
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Disconnect Game) - Error retrieving player data from queue: %v\n", name, pid, err)
-			continue
-		}
-		//playerData, err = redisClient.GetPlayer(playerData.ID)	// We cant do the get player, because it was already removed...
-		//log.Printf("[%s-%d] - Processing the disconnect from game: %+v\n", name, pid, playerData)
-		game, err := redisClient.GetGame(playerData.GameID)
-		if err != nil {
-			log.Printf("[%s-%d] - Error retrieving Game:%v\n", name, pid, err)
-			continue
-		}
-		redisClient.SaveDisconnectSessionPlayerData(*playerData, *game)
-		gamePlayer, _ := game.GetGamePlayer(playerData.ID)
-		// Now we notify the other player that this happened
-		msg, _ := messages.NewMessage("opponent_disconnected_game", "disconnected")
-		opponent, _ := game.GetOpponentGamePlayer(gamePlayer.ID)
-		redisClient.PublishToGamePlayer(*opponent, string(msg))
+// runGameCommand decodes the command and its data and runs its handler.
+func runGameCommand(payload string) error {
+	var command models.GameCommand
+	if err := json.Unmarshal([]byte(payload), &command); err != nil {
+		log.Printf("[%s-%d] - (Run Game Command) - JSON Unmarshal Error: %v\n", name, pid, err)
+		return err
+	}
+	var err error
+	switch command.Type {
+	case models.GameCommandMove:
+		var move models.Move
+		if err = json.Unmarshal(command.Data, &move); err == nil {
+			handleMove(command.GameID, move)
+		}
+	case models.GameCommandDraw:
+		var drawCommand models.DrawCommand
+		if err = json.Unmarshal(command.Data, &drawCommand); err == nil {
+			handleDrawCommand(command.GameID, drawCommand)
+		}
+	case models.GameCommandLeave:
+		var player models.Player
+		if err = json.Unmarshal(command.Data, &player); err == nil {
+			handleLeaveGame(command.GameID, &player)
+		}
+	case models.GameCommandDisconnect:
+		var player models.Player
+		if err = json.Unmarshal(command.Data, &player); err == nil {
+			handleDisconnectFromGame(command.GameID, &player)
+		}
+	case models.GameCommandReconnect:
+		var player models.Player
+		if err = json.Unmarshal(command.Data, &player); err == nil {
+			handleReconnectFromGame(command.GameID, &player)
+		}
+	case models.GameCommandTimeout:
+		handleClockExpired(command.GameID)
+	default:
+		err = fmt.Errorf("unknown command type %s", command.Type)
 	}
+	if err != nil {
+		log.Printf("[%s-%d] - (Run Game Command) - Game %s %s: %v\n", name, pid, command.GameID, command.Type, err)
+	}
+	return err
 }
 
-func processReconnectFromGame() {
-	for {
-		// Block until there is a game over message
-		playerData, err := redisClient.BLPop("reconnect_game", 0)
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Reconnect Game) - Error retrieving player data from queue: %v\n", name, pid, err)
-			continue
-		}
-		log.Printf("[%s-%d]  (Process Reconnect Game) - Processing the reconnect game: %+v\n", name, pid, playerData)
-		game, err := redisClient.GetGame(playerData.GameID)
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Reconnect Game) - Error retrieving game data from redis: %v\n", name, pid, err)
-			continue
-		}
-		// We send a message to the reconnected player with the board state.
-		msg, err := messages.GenerateGameReconnectMessage(*game)
-		if err != nil {
-			log.Printf("[%s-%d] - (Process Reconnect Game) - Error generating game reconnect message: %v\n", name, pid, err)
-			continue
-		}
-		err = redisClient.PublishToPlayer(*playerData, string(msg))
+// moveOutcome is what a move did to the game, it is worked out before the game is saved and the
+// players are only told once it is.
+type moveOutcome struct {
+	hops             []models.MoveResult // Hops applied.
+	failedHop        models.Move         // Hop that could not be applied when the move is rejected.
+	reason           string              // Game over reason when the move ended the game.
+	turnChanged      bool
+	drawOfferExpired bool
+}
+
+// playMove validates the move and plays it on the game, then ends the turn: the game is finished when
+// it is won or drawn, the player keeps the turn to continue capturing, or the turn changes.
+// Returns the error when the move is rejected, the game must then be dropped: a path with a hop that
+// can't be applied is rejected as a whole.
+func playMove(game *models.Game, move models.Move, receivedAt time.Time) (moveOutcome, error) {
+	// A move that arrives once the clock ran out is too late, the player lost on time.
+	if !game.IsOver() && move.PlayerID == game.CurrentPlayerID && game.ClockRemaining(receivedAt) <= 0 {
+		var outcome moveOutcome
+		outcome.reason, outcome.drawOfferExpired = runOutClock(game)
+		outcome.turnChanged = !game.IsOver()
+		return outcome, nil
+	}
+	// Clients can send a single hop, or the full path of the move to apply it in one go.
+	hops := []models.Move{move}
+	if len(move.Path) > 0 {
+		legalMove, err := game.ValidatePath(move)
 		if err != nil {
-			log.Printf("[%s-%d] - (Process Reconnect Game) - Error publishing game reconnect message: %v\n", name, pid, err)
-			continue
+			return moveOutcome{}, err
 		}
-		// We notify the opponent that the player reconnected.
-		opponent, _ := game.GetOpponentGamePlayer(playerData.ID)
-		msg, _ = messages.NewMessage("opponent_disconnected_game", "reconnected")
-		redisClient.PublishToGamePlayer(*opponent, string(msg))
-		redisClient.DeleteDisconnectedPlayerSession(playerData.SessionID)
+		hops = legalMove.Hops(move.PlayerID)
+	} else if err := game.ValidateMove(move); err != nil {
+		return moveOutcome{}, err
+	}
+	for i := range hops {
+		hops[i] = game.TimeMove(hops[i], receivedAt)
+	}
+	var outcome moveOutcome
+	results, failed, err := game.PlayHops(hops)
+	if err != nil {
+		// The hops already played are dropped with the game, the move is applied whole or not at all.
+		outcome.failedHop = failed.Move
+		return outcome, err
+	}
+	outcome.hops = results
+	turnContinues := results[len(results)-1].TurnContinues
+
+	// We check for game Over
+	if game.CheckGameOver() {
+		game.FinishGame(move.PlayerID)
+		outcome.reason = "winner"
+		return outcome, nil
+	}
+	// After a capture the player keeps the turn while the piece can keep capturing.
+	if turnContinues {
+		return outcome, nil
+	}
+	// The turn is over, before switching we check if the game ended in a draw.
+	if isDraw, drawRule := checkDraw(game); isDraw {
+		game.FinishGameAsDraw(drawRule)
+		outcome.reason = "draw"
+		return outcome, nil
 	}
+	outcome.reason, outcome.drawOfferExpired = changeTurn(game)
+	outcome.turnChanged = !game.IsOver()
+	return outcome, nil
 }
 
-func handleTurnChange(game *models.Game) {
-	// publishStopToTimerChannel(game.ID)
-	game.NextPlayer()
-	redisClient.UpdateGame(game)
-	msg, err := messages.NewMessage("turn_switch", game.CurrentPlayerID)
+// checkDraw records the position reached at the end of the turn and evaluates the configured draw rules.
+func checkDraw(game *models.Game) (bool, string) {
+	nextPlayerID, err := game.GetOpponentPlayerID(game.CurrentPlayerID)
 	if err != nil {
-		log.Printf("[%s-%d] - (Handle Turn Change) - Failed to generate for turn change: %v\n", name, pid, msg)
+		log.Printf("[%s-%d] - (Check Draw) - Failed to get next player: %v\n", name, pid, err)
+		return false, ""
 	}
-	BroadCastToGamePlayers(msg, *game)
-	publishSwitchToTimerChannel(game.ID) // Start a fresh timer or switch player timer.
+	gameworkerCfg := config.Cfg.Services["gameworker"]
+	rules := models.DrawRules{
+		Repetitions:          gameworkerCfg.DrawRepetitions,
+		MovesWithoutProgress: gameworkerCfg.DrawMovesWithoutProgress,
+		KingVsKing:           gameworkerCfg.DrawKingVsKing,
+	}
+	game.RecordPosition(nextPlayerID)
+	return game.CheckDraw(rules, nextPlayerID)
 }
 
-func publishStopToTimerChannel(gameID string) {
-	stopChannel := fmt.Sprintf("game:%s:stop_timer", gameID)
-	redisClient.Client.Publish(context.Background(), stopChannel, "STOP") // Stop the old timer
+func handleLeaveGame(gameID string, playerData *models.Player) {
+	game, err := redisClient.GetGame(gameID)
+	if err != nil {
+		log.Printf("[%s-%d] - Error retrieving Game:%v\n", name, pid, err)
+		return
+	}
+	winnrID, _ := game.GetOpponentPlayerID(playerData.ID)
+	handleGameEnd(game.ID, "player_left", winnrID)
 }
 
-func publishSwitchToTimerChannel(gameID string) {
-	switchChannel := fmt.Sprintf("game:%s:switch", gameID)
-	redisClient.Client.Publish(context.Background(), switchChannel, "SWITCH") // This will let the timer know there was a change.
+func handleDisconnectFromGame(gameID string, playerData *models.Player) {
+	game, err := redisClient.GetGame(gameID)
+	if err != nil {
+		log.Printf("[%s-%d] - Error retrieving Game:%v\n", name, pid, err)
+		return
+	}
+	redisClient.SaveDisconnectSessionPlayerData(*playerData, *game)
+	gamePlayer, _ := game.GetGamePlayer(playerData.ID)
+	// Now we notify the other player that this happened
+	msg, _ := messages.NewMessage("opponent_disconnected_game", "disconnected")
+	opponent, _ := game.GetOpponentGamePlayer(gamePlayer.ID)
+	redisClient.PublishToGamePlayer(*opponent, string(msg))
 }
 
-func startTimer(game *models.Game) {
-	switch game.TimerSetting {
-	case "reset":
-		startResetEveryTurnTimer(game)
-	case "cumulative":
-		startCumulativeTimer(game)
-	default:
-		log.Printf("Invalid timer setting: %s for game %s\n", game.TimerSetting, game.ID)
+func handleReconnectFromGame(gameID string, playerData *models.Player) {
+	log.Printf("[%s-%d]  (Handle Reconnect Game) - Processing the reconnect game: %+v\n", name, pid, playerData)
+	game, err := redisClient.GetGame(gameID)
+	if err != nil {
+		log.Printf("[%s-%d] - (Handle Reconnect Game) - Error retrieving game data from redis: %v\n", name, pid, err)
+		return
 	}
+	// We send a message to the reconnected player with the board state.
+	msg, err := messages.GenerateGameReconnectMessage(*game)
+	if err != nil {
+		log.Printf("[%s-%d] - (Handle Reconnect Game) - Error generating game reconnect message: %v\n", name, pid, err)
+		return
+	}
+	err = redisClient.PublishToPlayer(*playerData, string(msg))
+	if err != nil {
+		log.Printf("[%s-%d] - (Handle Reconnect Game) - Error publishing game reconnect message: %v\n", name, pid, err)
+		return
+	}
+	// We notify the opponent that the player reconnected.
+	opponent, _ := game.GetOpponentGamePlayer(playerData.ID)
+	msg, _ = messages.NewMessage("opponent_disconnected_game", "reconnected")
+	redisClient.PublishToGamePlayer(*opponent, string(msg))
+	redisClient.DeleteDisconnectedPlayerSession(playerData.SessionID)
 }
 
-func startResetEveryTurnTimer(game *models.Game) {
-	ctx := context.Background()
-	stopChannel := fmt.Sprintf("game:%s:stop_timer", game.ID)
-	switchChannel := fmt.Sprintf("game:%s:switch", game.ID) // Channel to listen for switch events
-
-	// Subscribe to both stop and switch channels
-	pubsub := redisClient.Client.Subscribe(ctx, stopChannel, switchChannel)
-	defer pubsub.Close()
-
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
-
-	// Fetch the base timer from the config.
-	baseTimer := config.Cfg.Services["gameworker"].Timer
+func handleDrawCommand(gameID string, drawCommand models.DrawCommand) {
+	var offeredBy string
+	var invalid error
+	game, err := redisClient.UpdateGameFunc(gameID, func(game *models.Game) error {
+		invalid = answerDrawCommand(game, drawCommand, &offeredBy)
+		return invalid
+	})
+	if invalid != nil {
+		log.Printf("[%s-%d] - (Handle Draw Command) - Invalid draw command: %v\n", name, pid, invalid)
+		msg, _ := messages.GenerateGenericMessage("invalid", invalid.Error())
+		redisClient.PublishToPlayerID(drawCommand.PlayerID, string(msg))
+		return
+	}
+	if err != nil {
+		log.Printf("[%s-%d] - (Handle Draw Command) - Failed to update game!: %v\n", name, pid, err)
+		return
+	}
 
-	// Find the player with Color == "b" to start the timer
-	var activePlayerIndex int
-	for i, player := range game.Players {
-		if player.Color == "b" {
-			activePlayerIndex = i
-			break
+	switch drawCommand.Command {
+	case "offer_draw":
+		opponent, err := game.GetOpponentGamePlayer(drawCommand.PlayerID)
+		if err != nil {
+			log.Printf("[%s-%d] - (Handle Draw Command) - Failed to get opponent!: %v\n", name, pid, err)
+			return
 		}
+		msg, _ := messages.NewMessage("offer_draw", drawCommand.PlayerID)
+		redisClient.PublishToGamePlayer(*opponent, string(msg))
+	case "accept_draw":
+		closeGame(game, "draw")
+	case "decline_draw":
+		msg, _ := messages.NewMessage("decline_draw", drawCommand.PlayerID)
+		redisClient.PublishToPlayerID(offeredBy, string(msg))
 	}
-	//activePlayer := game.Players[activePlayerIndex]	// This was used in the log prints.
-	// Initialize the timer for the active player
-	timer := baseTimer
-	for {
-		select {
-		case <-ticker.C:
-			// Decrement the timer
-			timer--
-			activePlayerTimer := timer
-
-			// Publish the updated timer to both players
-			msg, _ := messages.GenerateGameTimerMessage(*game, activePlayerTimer)
-			if isEven(timer) {
-				redisClient.PublishToGamePlayer(game.Players[0], string(msg))
-				redisClient.PublishToGamePlayer(game.Players[1], string(msg))
-			}
+}
 
-			// Check if the timer has expired
-			if activePlayerTimer <= 0 {
-				handleTurnChange(game)
-				//log.Printf("Turn timer expired for player %s in game %s. Switching turns.\n", activePlayer.ID, game.ID)
-			}
+// answerDrawCommand applies the draw command to the game, an accepted offer finishes the game as a draw.
+// offeredBy is set to the player whose offer was declined.
+func answerDrawCommand(game *models.Game, drawCommand models.DrawCommand, offeredBy *string) error {
+	if game.IsOver() {
+		return errGameOver
+	}
+	switch drawCommand.Command {
+	case "offer_draw":
+		return game.OfferDraw(drawCommand.PlayerID, config.Cfg.Services["gameworker"].DrawOffersPerGame)
+	case "accept_draw":
+		if err := game.AcceptDraw(drawCommand.PlayerID); err != nil {
+			return err
+		}
+		game.FinishGameAsDraw("agreement")
+		return nil
+	case "decline_draw":
+		var err error
+		*offeredBy, err = game.DeclineDraw(drawCommand.PlayerID)
+		return err
+	default:
+		return fmt.Errorf("unknown draw command %s", drawCommand.Command)
+	}
+}
 
-		case msg := <-pubsub.Channel():
-			switch msg.Channel {
-			case stopChannel:
-				//log.Printf("Timer stopped for game %s\n", game.ID)
-				return // Exit the function, stopping the timer
-
-			case switchChannel:
-				// Switch the active player when a move is made
-				activePlayerIndex = 1 - activePlayerIndex // Toggle between 0 and 1
-				//activePlayer = game.Players[activePlayerIndex] 	// This was used in the log prints.
-				timer = baseTimer // Reset the timer for the new active player
-				//log.Printf("Switched active player to %s in game %s. Timer reset to %d seconds.\n", activePlayer.ID, game.ID, timer)
-			}
+// processHintRequests answers the players asking for the best move in their practice games.
+func processHintRequests(ctx context.Context) {
+	redisClient.ConsumePlayers(ctx, "request_hint", name, consumer, func(playerData *models.Player) error {
+		player, err := redisClient.GetPlayer(playerData.ID)
+		if err != nil {
+			log.Printf("[%s-%d] - (Process Hint Requests) - Failed to get player!: %v\n", name, pid, err)
+			return err
 		}
+		game, err := redisClient.GetGame(player.GameID)
+		if err != nil {
+			log.Printf("[%s-%d] - (Process Hint Requests) - Failed to get game!: %v\n", name, pid, err)
+			return err
+		}
+		// The search takes up to engine.HintMaxTime, the next requests don't wait for it.
+		hints.Add(1)
+		go func() {
+			defer hints.Done()
+			answerHint(player, game)
+		}()
+		return nil
+	})
+}
+
+func answerHint(player *models.Player, game *models.Game) {
+	hint, err := findHint(player, game)
+	if err != nil {
+		log.Printf("[%s-%d] - (Answer Hint) - Hint refused: %v\n", name, pid, err)
+		msg, _ := messages.GenerateGenericMessage("invalid", err.Error())
+		redisClient.PublishToPlayer(*player, string(msg))
+		return
+	}
+	msg, err := messages.NewMessage("hint", hint)
+	if err != nil {
+		log.Printf("[%s-%d] - (Answer Hint) - Failed to generate message: %v\n", name, pid, err)
+		return
 	}
+	redisClient.PublishToPlayer(*player, string(msg))
 }
 
-func startCumulativeTimer(game *models.Game) {
-	ctx := context.Background()
-	stopChannel := fmt.Sprintf("game:%s:stop_timer", game.ID)
-	switchChannel := fmt.Sprintf("game:%s:switch", game.ID) // Channel to listen for move events
+// findHint searches the best move for the player, hints are only given in practice games, on the
+// player turn, and are limited by the hint_cooldown and hints_per_game settings.
+func findHint(player *models.Player, game *models.Game) (engine.Hint, error) {
+	if !game.IsPractice {
+		return engine.Hint{}, fmt.Errorf("hints are only available in practice games")
+	}
+	if game.CurrentPlayerID != player.ID {
+		return engine.Hint{}, fmt.Errorf("hints are only available on your turn")
+	}
+	settings := config.Cfg.Services["gameworker"]
+	started, err := redisClient.StartHintCooldown(player.ID, time.Duration(settings.HintCooldown)*time.Second)
+	if err != nil {
+		return engine.Hint{}, err
+	}
+	if !started {
+		return engine.Hint{}, fmt.Errorf("wait %d seconds between hints", settings.HintCooldown)
+	}
+	used, err := redisClient.IncrementHintsUsed(game.ID, player.ID)
+	if err != nil {
+		return engine.Hint{}, err
+	}
+	if settings.HintsPerGame > 0 && used > int64(settings.HintsPerGame) {
+		return engine.Hint{}, fmt.Errorf("no hints left, %d hints per game", settings.HintsPerGame)
+	}
 
-	// Subscribe to both stop and move channels
-	pubsub := redisClient.Client.Subscribe(ctx, stopChannel, switchChannel)
-	defer pubsub.Close()
+	side, _ := game.Board.SideOf(player.ID)
+	var analysis engine.BoardAnalysis
+	if game.Continuation != nil {
+		analysis = engine.AnalyzeContinuation(&game.Board, side, game.Continuation.Square, engine.HintDepth, engine.HintMaxTime)
+	} else {
+		analysis = engine.AnalyzeBoard(&game.Board, side, engine.HintDepth, engine.HintMaxTime)
+	}
+	if len(analysis.BestMoves) == 0 {
+		return engine.Hint{}, fmt.Errorf("there are no legal moves")
+	}
+	return analysis.BestMoves[0], nil
+}
 
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+// changeTurn passes the turn to the opponent, who loses right away when blocked. Returns the game over
+// reason when the game ended, and if a draw offer expired with the turn.
+func changeTurn(game *models.Game) (string, bool) {
+	drawOfferExpired := game.DrawOffer != nil
+	game.NextPlayer()
+	// A player that can't move loses, there is no point in waiting for the timer to run out.
+	if game.IsCurrentPlayerBlocked() {
+		winnerID, _ := game.GetOpponentPlayerID(game.CurrentPlayerID)
+		game.FinishGame(winnerID)
+		return "no_moves", false
+	}
+	return "", drawOfferExpired
+}
 
-	// Initialize timers for each player
-	playerTimers := make(map[string]int) // Key: Player ID, Value: Remaining time
-	for _, player := range game.Players {
-		playerTimers[player.ID] = player.Timer
+// runOutClock ends the turn of the player whose time ran out: the turn is passed in reset mode and the
+// game is lost in the other modes.
+func runOutClock(game *models.Game) (string, bool) {
+	if game.TimeControl.PassesTurnOnTimeout() {
+		return changeTurn(game)
 	}
+	winner, _ := game.GetOpponentPlayerID(game.CurrentPlayerID)
+	game.FinishGame(winner)
+	return "timeout", false
+}
 
-	// Find the player with Color == "b" to start the timer
-	var activePlayerIndex int
-	for i, player := range game.Players {
-		if player.Color == "b" {
-			activePlayerIndex = i
-			break
-		}
+// announceTurn tells the players about a turn change once the game is saved, and starts the clock.
+func announceTurn(game *models.Game, drawOfferExpired bool) {
+	msg, err := messages.NewMessage("turn_switch", game.CurrentPlayerID)
+	if err != nil {
+		log.Printf("[%s-%d] - (Handle Turn Change) - Failed to generate for turn change: %v\n", name, pid, msg)
 	}
-	activePlayer := game.Players[activePlayerIndex]
+	BroadCastToGamePlayers(msg, *game)
+	if drawOfferExpired {
+		msg, _ = messages.NewMessage("draw_offer_expired", true)
+		BroadCastToGamePlayers(msg, *game)
+	}
+	startClock(game) // Start the clock of the new turn.
+}
 
+// startClock sets the deadline of the current turn, any gameworker fires it in processGameClocks.
+func startClock(game *models.Game) {
+	if err := redisClient.SetGameDeadline(game.ID, game.ClockDeadline()); err != nil {
+		log.Printf("[%s-%d] - (Start Clock) - %v\n", name, pid, err)
+	}
+}
+
+// processGameClocks runs the game clocks kept in redis, so they survive the gameworker that started
+// them. Each expired deadline is fired once, as a timeout command in the game mailbox, and the
+// gameworker that claims each second sends the timers of every game to the players.
+func processGameClocks(ctx context.Context) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
 	for {
+		var now time.Time
 		select {
-		case <-ticker.C:
-			// Decrement the active player's timer
-			playerTimers[activePlayer.ID]--
-			activePlayerTimer := playerTimers[activePlayer.ID]
-
-			// Publish the updated timer to both players
-			game, _ := redisClient.GetGame(game.ID)
-			msg, _ := messages.GenerateGameTimerMessage(*game, activePlayerTimer)
-			game.UpdatePlayerTimer(activePlayer.ID, activePlayerTimer)
-			go redisClient.UpdateGame(game)
-			go redisClient.PublishToGamePlayer(game.Players[0], string(msg))
-			go redisClient.PublishToGamePlayer(game.Players[1], string(msg))
-
-			// Check if the active player's timer has expired
-			if activePlayerTimer <= 0 {
-				// The other player wins
-				winner := game.Players[1-activePlayerIndex].ID
-				//log.Printf("Cumulative timer expired for player %s in game %s. Player %s wins.\n", activePlayer.ID, game.ID, winner)
-				handleGameEnd(game, "timeout", winner)
-				return
-			}
+		case <-ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+		if _, err := redisClient.FireExpiredGames(now); err != nil {
+			log.Printf("[%s-%d] - (Process Game Clocks) - %v\n", name, pid, err)
+		}
+		if tick, err := redisClient.ClaimClockTick(now); err == nil && tick {
+			broadcastGameTimers(now)
+		}
+	}
+}
 
-		case msg := <-pubsub.Channel():
-			switch msg.Channel {
-			case stopChannel:
-				//log.Printf("Timer stopped for game %s\n", game.ID)
-				return // Exit the function, stopping the timer
-
-			case switchChannel:
-				// Switch the active player when a switch is sent
-				activePlayerIndex = 1 - activePlayerIndex // Toggle between 0 and 1
-				activePlayer = game.Players[activePlayerIndex]
-				//log.Printf("Switched active player to %s in game %s\n", activePlayer.ID, game.ID)
-			}
+// handleClockExpired passes the turn in reset mode and ends the game in the other modes, unless the
+// turn changed after the deadline was claimed.
+func handleClockExpired(gameID string) {
+	var reason string
+	var drawOfferExpired bool
+	game, err := redisClient.UpdateGameFunc(gameID, func(game *models.Game) error {
+		if game.IsOver() {
+			return errGameOver
+		}
+		if game.ClockRemaining(time.Now()) > 0 {
+			return errClockRunning
+		}
+		reason, drawOfferExpired = runOutClock(game)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errClockRunning):
+		startClock(game)
+	case errors.Is(err, errGameOver), errors.Is(err, redisdb.ErrGameNotFound):
+	case err != nil:
+		// The deadline was removed when it fired, put it back so the timeout is tried again.
+		log.Printf("[%s-%d] - (Handle Clock Expired) - Game %s: %v\n", name, pid, gameID, err)
+		if err := redisClient.SetGameDeadline(gameID, time.Now()); err != nil {
+			log.Printf("[%s-%d] - (Handle Clock Expired) - %v\n", name, pid, err)
+		}
+	case game.IsOver():
+		closeGame(game, reason)
+	default:
+		announceTurn(game, drawOfferExpired)
+	}
+}
+
+// broadcastGameTimers sends the time left to the current player of every game with a running clock.
+func broadcastGameTimers(now time.Time) {
+	gameIDs, err := redisClient.GetClockedGames()
+	if err != nil {
+		log.Printf("[%s-%d] - (Broadcast Game Timers) - %v\n", name, pid, err)
+		return
+	}
+	for _, gameID := range gameIDs {
+		game, err := redisClient.GetGame(gameID)
+		if err != nil {
+			continue
 		}
+		msg, _ := messages.GenerateGameTimerMessage(*game, max(game.ClockRemaining(now), 0))
+		BroadCastToGamePlayers(msg, *game)
 	}
 }
 
-func handleGameEnd(game *models.Game, reason string, winnerID string) {
-	//log.Printf("Handling Game End for game [%v] - reason: [%v]", game.ID, reason)
+// handleGameEnd finishes the game with the winner and closes it, unless another update finished it
+// first, e.g. a timeout and the last move at the same time.
+func handleGameEnd(gameID string, reason string, winnerID string) {
+	game, err := redisClient.UpdateGameFunc(gameID, func(game *models.Game) error {
+		if game.IsOver() {
+			return errGameOver
+		}
+		game.FinishGame(winnerID)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[%s-%d] - (Handle Game End) - Game %s: %v\n", name, pid, gameID, err)
+		return
+	}
+	closeGame(game, reason)
+}
 
-	publishStopToTimerChannel(game.ID)
-	game.FinishGame(winnerID)
+// closeGame notifies and pays out the players of a finished game, then moves it from redis to postgres.
+// Only the update that saved the game finished calls it, so a game is closed once.
+func closeGame(game *models.Game, reason string) {
+	if err := redisClient.RemoveGameDeadline(game.ID); err != nil {
+		log.Printf("[%s-%d] - (Handle Game Over) - %v\n", name, pid, err)
+	}
+	winnerID := game.Winner
 
 	winAmount := interfaces.CalculateWinAmount(int64(game.BetValue*100), game.OperatorIdentifier.WinFactor)
+	if game.IsDraw() {
+		winAmount = int64(game.BetValue * 100)
+	}
 	gameOverMsg, err := messages.GenerateGameOverMessage(reason, *game, winAmount)
 	if err != nil {
 		log.Printf("[%s] - (Handle Game Over) - Failed to generate game over message!: %v\n", name, err)
@@ -454,9 +778,17 @@ func handleGameEnd(game *models.Game, reason string, winnerID string) {
 	}
 	p1 := game.Players[0]
 	p2 := game.Players[1]
-	go cleanUpGameDisconnectedPlayers(*game) // This was at the end, was moved up here, might make the reconect when the game is over more smooth...?
-	go processGameEndForPlayer(winnerID, game, p1, reason, winAmount, gameOverMsg)
-	go processGameEndForPlayer(winnerID, game, p2, reason, winAmount, gameOverMsg)
+	gameEnds.Add(3)
+	go func() {
+		defer gameEnds.Done()
+		cleanUpGameDisconnectedPlayers(*game) // This was at the end, was moved up here, might make the reconect when the game is over more smooth...?
+	}()
+	for _, gamePlayer := range []models.GamePlayer{p1, p2} {
+		go func() {
+			defer gameEnds.Done()
+			processGameEndForPlayer(winnerID, game, gamePlayer, reason, winAmount, gameOverMsg)
+		}()
+	}
 
 	// since the game is Over, we remove it from redis.
 	if redisClient.RemoveGame(game.ID) != nil {
@@ -472,23 +804,28 @@ func processGameEndForPlayer(winnerID string, game *models.Game, gamePlayer mode
 	interfaceModule = interfaces.OperatorModules[game.OperatorIdentifier.OperatorName]
 	var balanceUpdateMsg []byte
 
-	// 1. The Winner needs to have a post to the wallet.
-	if winnerID == gamePlayer.ID {
+	// 1. The Winner needs to have a post to the wallet, on a draw both players get their bet back.
+	// Bots have no wallet, and practice games are free.
+	if (winnerID == gamePlayer.ID || game.IsDraw()) && !gamePlayer.IsBot && !game.IsPractice {
 		// Get the session from the ID, since they share the same ID.
-		winnerSession, err := redisClient.GetSessionByID(winnerID)
+		playerSession, err := redisClient.GetSessionByID(gamePlayer.ID)
 		if err != nil {
-			log.Printf("[GameWorker] - error -> handleGameEnd - processGameEndForPlayer: fetching winner player session:%s\n", err)
+			log.Printf("[GameWorker] - error -> handleGameEnd - processGameEndForPlayer: fetching player session:%s\n", err)
 			return
 		}
-		if winnerSession == nil {
+		if playerSession == nil {
 			log.Printf("[GameWorker] - error -> handleGameEnd - processGameEndForPlayer: session id is nill!:%s\n", err)
 			return
 		}
-		// we use our winner session here, because this way the winner will be payed out even if offline.
+		// we use our player session here, because this way the player will be payed out even if offline.
 		var newBalance int64
-		newBalance, _, err = interfaceModule.HandlePostWin(postgresClient, redisClient, *winnerSession, int64(game.BetValue*100), game.ID)
+		if game.IsDraw() {
+			newBalance, err = interfaceModule.HandlePostRefund(postgresClient, redisClient, *playerSession, int64(game.BetValue*100), game.ID)
+		} else {
+			newBalance, _, err = interfaceModule.HandlePostWin(postgresClient, redisClient, *playerSession, int64(game.BetValue*100), game.ID)
+		}
 		if err != nil {
-			log.Printf("[RoomWorker] - Error posting the win :%s\n", err)
+			log.Printf("[GameWorker] - Error posting the game result to the wallet :%s\n", err)
 		} else {
 			// We then generate the balance update message and send it over to the game Player. The player can be offline, but I guess the message just wont get delivered.
 			// I could try to fetch the player from redis...? Is it worth it?... I fetch the player down the line...
@@ -531,45 +868,6 @@ func cleanUpGameDisconnectedPlayers(game models.Game) {
 
 }
 
-func validMove(game *models.Game, move models.Move, piece *models.Piece) bool {
-	capturers := game.Board.PiecesThatCanCapture(game.CurrentPlayerID)
-	// If captures are available, and this piece can't capture, reject the move
-	if len(capturers) > 0 {
-		canCapture := false
-		for _, p := range capturers {
-			if p.PieceID == piece.PieceID {
-				canCapture = true
-				break
-			}
-		}
-		if !canCapture {
-			log.Println("Error: there are player pieces that can capture, must move one of those.")
-			return false
-		}
-	}
-
-	var valid bool
-	var err error
-	game.Board.PiecesThatCanCapture(game.CurrentPlayerID)
-	if piece.IsKinged {
-		valid, err = game.Board.IsValidMoveKing(move)
-	} else {
-		valid, err = game.Board.IsValidMove(move)
-	}
-	if err != nil {
-		log.Printf("Error: %v", err)
-		return false
-	}
-	if !valid {
-		return false
-	}
-	return true
-}
-
-func isEven(n int) bool {
-	return n&1 == 0 // Last bit = 0 → even
-}
-
 func BroadCastToGamePlayers(msg []byte, game models.Game) {
 	redisClient.PublishToGamePlayer(game.Players[0], string(msg))
 	redisClient.PublishToGamePlayer(game.Players[1], string(msg))
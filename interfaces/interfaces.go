@@ -21,6 +21,7 @@ type OperatorInterface interface {
 	HandleFetchWalletBalance(s models.Session, rc *redisdb.RedisClient) (int64, error)
 	HandlePostBet(pgs *postgrescli.PostgresCli, rc *redisdb.RedisClient, session models.Session, betValue int64, gameID string) (int64, error)
 	HandlePostWin(pgs *postgrescli.PostgresCli, rc *redisdb.RedisClient, session models.Session, betValue int64, gameID string) (int64, int64, error)
+	HandlePostRefund(pgs *postgrescli.PostgresCli, rc *redisdb.RedisClient, session models.Session, betValue int64, gameID string) (int64, error)
 }
 
 // OperatorModules maps operator names to their respective modules
@@ -215,6 +216,66 @@ func (m *SokkerDuelModule) HandlePostWin(pgs *postgrescli.PostgresCli, rc *redis
 	return int64(fbalance * 100), winnings, nil
 }
 
+// HandlePostRefund gives the player the bet back, used when a game ends in a draw.
+//
+// SokkerDuel has no refund endpoint, the refund is credited through the win endpoint
+// with the bet value, and saved as a 'refund' transaction.
+func (m *SokkerDuelModule) HandlePostRefund(pgs *postgrescli.PostgresCli, rc *redisdb.RedisClient, session models.Session, betValue int64, gameID string) (int64, error) {
+	// Validate input parameters
+	if betValue <= 0 {
+		return -1, fmt.Errorf("invalid refund value: %d", betValue)
+	}
+	if gameID == "" {
+		return -1, fmt.Errorf("empty game ID")
+	}
+	if session.ID == "" {
+		return -1, fmt.Errorf("invalid session")
+	}
+	refundData := models.SokkerDuelWin{
+		OperatorGameName: session.OperatorIdentifier.GameName,
+		Currency:         session.Currency,
+		Amount:           betValue,
+		TransactionID:    models.GenerateUUID(),
+		RoundID:          gameID,
+		ExtractID:        session.ExtractID,
+	}
+	refundResponse, err := walletrequests.SokkerDuelPostWin(session, refundData)
+	if err != nil {
+		if saveErr := saveFailedRefundTransaction(pgs, session, refundData, err, gameID); saveErr != nil {
+			return -1, fmt.Errorf("API error: %v | Transaction save error: %v", err, saveErr)
+		}
+		return -1, err // Return original API error
+	}
+	trans := models.Transaction{
+		ID:          refundData.TransactionID,
+		SessionID:   session.ID,
+		Type:        "refund",
+		Amount:      betValue,
+		Currency:    session.Currency,
+		Platform:    "sokkerpro",
+		Operator:    "SokkerDuel",
+		Client:      session.PlayerName,
+		Game:        session.OperatorIdentifier.GameName,
+		RoundID:     gameID,
+		Timestamp:   time.Now(),
+		Status:      refundResponse.Status,
+		Description: string(mustMarshal(refundResponse)),
+	}
+	if err := pgs.SaveTransaction(trans); err != nil {
+		return -1, fmt.Errorf("failed to save transaction: %v", err)
+	}
+	// Reset ExtractID in session, the round is closed.
+	session.ExtractID = 0
+	if err := rc.AddSession(&session); err != nil {
+		return -1, fmt.Errorf("failed to save session: %v", err)
+	}
+	fbalance, err := strconv.ParseFloat(refundResponse.Data.Balance, 64)
+	if err != nil {
+		return -1, fmt.Errorf("failed to parse balance: %v", err)
+	}
+	return int64(fbalance * 100), nil
+}
+
 // Helper function to save failed transactions
 func saveFailedBetTransaction(pgs *postgrescli.PostgresCli, session models.Session, betData models.SokkerDuelBet, apiError error, gameID string) error {
 	trans := models.Transaction{
@@ -254,6 +315,25 @@ func saveFailedWinTransaction(pgs *postgrescli.PostgresCli, session models.Sessi
 	return pgs.SaveTransaction(trans)
 }
 
+func saveFailedRefundTransaction(pgs *postgrescli.PostgresCli, session models.Session, refundData models.SokkerDuelWin, apiError error, gameID string) error {
+	trans := models.Transaction{
+		ID:          refundData.TransactionID,
+		SessionID:   session.ID,
+		Type:        "refund",
+		Amount:      refundData.Amount,
+		Currency:    session.Currency,
+		Platform:    "sokkerpro",
+		Operator:    "SokkerDuel",
+		Client:      session.PlayerName,
+		Game:        session.OperatorIdentifier.GameName,
+		RoundID:     gameID,
+		Timestamp:   time.Now(),
+		Status:      "error",
+		Description: apiError.Error(),
+	}
+	return pgs.SaveTransaction(trans)
+}
+
 func generateGameURL(baseURL, token, sessionID, currency string) (string, error) {
 	// Parse the base URL
 	parsedURL, err := url.Parse(baseURL)
@@ -283,6 +363,8 @@ func generatePlayerSession(op models.Operator, token, username, currency string,
 			OperatorGameName: op.OperatorGameName,
 			GameName:         op.GameName,
 			WinFactor:        op.WinFactor,
+			Variant:          op.Variant,
+			TimeControl:      op.TimeControl,
 		},
 		OperatorBaseUrl: op.OperatorWalletBaseUrl,
 		CreatedAt:       time.Now(),
@@ -395,3 +477,6 @@ func (m *TestModule) HandlePostBet(pgs *postgrescli.PostgresCli, rc *redisdb.Red
 func (m *TestModule) HandlePostWin(pgs *postgrescli.PostgresCli, rc *redisdb.RedisClient, session models.Session, winValue int64, gameID string) (int64, int64, error) {
 	return 199, 99, nil
 }
+func (m *TestModule) HandlePostRefund(pgs *postgrescli.PostgresCli, rc *redisdb.RedisClient, session models.Session, betValue int64, gameID string) (int64, error) {
+	return 100, nil
+}
@@ -15,6 +15,7 @@ type CommandInfo struct {
 var validCommands = map[string]CommandInfo{
 	"queue":       {Type: ClientCommand}, // This adds the player to a queue, there is a small issue with a single player in a queue, but i believe that has been handled.
 	"ready_queue": {Type: ClientCommand}, // This allows the player to issue a ready when in a room. Opponent receives an opponent_ready message
+	"queue_bot":   {Type: ClientCommand}, // Same as queue, but the player is paired with a bot of the selected difficulty.
 	"leave_queue": {Type: ClientCommand}, // This allows the player to leave the queue.
 	"leave_room":  {Type: ClientCommand}, // This allows the player to leave the room. The opponent gets placed in the Queue, and received a ready_queue message
 	"leave_game":  {Type: ClientCommand}, // This allows the player to leave the game. The opponent wins the game
@@ -25,6 +26,14 @@ var validCommands = map[string]CommandInfo{
 
 	"move_piece":   {Type: ClientCommand}, // This is issued by the cliente to trigger the movement of a piece.
 	"invalid_move": {Type: ServerCommand}, // This is issued by the cliente to trigger the movement of a piece.
+	"move_result":  {Type: ServerCommand}, // Sent to both players after each hop, with the captured piece, promotion and if the turn continues.
+
+	"offer_draw":   {Type: ClientCommand}, // This offers a draw to the opponent, the opponent receives an offer_draw message.
+	"accept_draw":  {Type: ClientCommand}, // This accepts the opponent draw offer, the game ends as a draw.
+	"decline_draw": {Type: ClientCommand}, // This declines the opponent draw offer, the opponent receives a decline_draw message.
+
+	"request_hint": {Type: ClientCommand}, // Asks the engine for the best move, only in practice games and on the player turn.
+	"hint":         {Type: ServerCommand}, // The best move for the player that asked for a hint, with its score.
 
 	"message":                    {Type: ServerCommand}, // issues when a player connects.
 	"connected":                  {Type: ServerCommand}, // issues when a player connects.
@@ -40,6 +49,8 @@ var validCommands = map[string]CommandInfo{
 	"game_over":                  {Type: ServerCommand}, // Sent when server detects a game over.
 	"turn_switch":                {Type: ServerCommand}, // Sent when the server detects a turn switch.
 	"balance_update":             {Type: ServerCommand}, // Sent when there is a change to a players money.
+	"draw_offer_expired":         {Type: ServerCommand}, // Sent when a pending draw offer expires at the turn change.
+	"server_shutdown":            {Type: ServerCommand}, // Sent before the wsapi closes the connection to shut down, the client should reconnect.
 
 	"game_info": {Type: BroadcastCommand}, // Sent with generic game info to feed the clientes.
 }
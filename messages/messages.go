@@ -2,6 +2,7 @@ package messages
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -37,6 +38,13 @@ type GameStartMessage struct {
 	CurrentPlayerID string
 	GamePlayers     []GamePlayerResponse
 	WinFactor       float64 `json:"win_factor"`
+	Variant         string  `json:"variant"`
+	BoardSize       int     `json:"board_size"`
+	IsPractice      bool    `json:"is_practice,omitempty"`
+	// Clock of the game, MaxTimer is its base.
+	TimeControl models.TimeControl `json:"time_control"`
+	// Piece that must keep capturing, only sent mid capture sequence.
+	Continuation *models.Continuation `json:"continuation,omitempty"`
 }
 
 type GameUpdatetMessage struct {
@@ -56,6 +64,22 @@ type GameOver struct {
 	Turns    int                `json:"turns"`
 	Winnings float64            `json:"winnings"`
 	GameTime time.Duration      `json:"game_time"`
+	IsDraw   bool               `json:"is_draw"`
+	DrawRule string             `json:"draw_rule,omitempty"`
+}
+
+// InvalidMove tells the player why the move was rejected, with the board state to resync the client.
+type InvalidMove struct {
+	Code       models.MoveErrorCode `json:"code"`
+	Reason     string               `json:"reason"`
+	Move       models.Move          `json:"move"`
+	BoardState GameStartMessage     `json:"board_state"`
+}
+
+// BotQueueValue is the value of a queue_bot command, the player is paired with a bot at the bet.
+type BotQueueValue struct {
+	BetValue   float64 `json:"bet_value"`
+	Difficulty string  `json:"difficulty"` // See engine.Difficulties, empty for the default one.
 }
 
 type GenericMessage struct {
@@ -216,43 +240,56 @@ func ConvertGamePlayersToResponse(players []models.GamePlayer) []GamePlayerRespo
 	return result
 }
 
-func GenerateGameStartMessage(game models.Game) ([]byte, error) {
+// newGameStartMessage builds the game state shared by the game_start, board_state and game_reconnect messages.
+func newGameStartMessage(game models.Game) GameStartMessage {
 	maxTimer, _ := game.CalcGameMaxTimer()
-	gamestart := GameStartMessage{
+	// The stored timers are the clocks at the start of the turn, the current player gets the time left.
+	players := ConvertGamePlayersToResponse(game.Players)
+	for i := range players {
+		if players[i].ID == game.CurrentPlayerID {
+			players[i].Timer = max(game.ClockRemaining(time.Now()), 0)
+		}
+	}
+	return GameStartMessage{
 		GameID:          game.ID,
 		Board:           game.Board.Grid,
 		MaxTimer:        maxTimer,
 		CurrentPlayerID: game.CurrentPlayerID,
-		GamePlayers:     ConvertGamePlayersToResponse(game.Players),
+		GamePlayers:     players,
 		WinFactor:       game.OperatorIdentifier.WinFactor,
+		Variant:         game.Board.Rules().Name,
+		BoardSize:       game.Board.Rules().BoardSize,
+		IsPractice:      game.IsPractice,
+		TimeControl:     game.TimeControl,
+		Continuation:    game.Continuation,
 	}
+}
+
+func GenerateGameStartMessage(game models.Game) ([]byte, error) {
+	gamestart := newGameStartMessage(game)
 	return NewMessage("game_start", gamestart)
 }
 
 func GenerateGameBoardState(game models.Game) ([]byte, error) {
-	maxTimer, _ := game.CalcGameMaxTimer()
-	gamestart := GameStartMessage{
-		GameID:          game.ID,
-		Board:           game.Board.Grid,
-		MaxTimer:        maxTimer,
-		CurrentPlayerID: game.CurrentPlayerID,
-		GamePlayers:     ConvertGamePlayersToResponse(game.Players),
-		WinFactor:       game.OperatorIdentifier.WinFactor,
-	}
+	gamestart := newGameStartMessage(game)
 	return NewMessage("board_state", gamestart)
 }
 
-func GenerateGameReconnectMessage(game models.Game) ([]byte, error) {
-	maxTimer, _ := game.CalcGameMaxTimer()
-
-	gamestart := GameStartMessage{
-		GameID:          game.ID,
-		Board:           game.Board.Grid,
-		MaxTimer:        maxTimer,
-		CurrentPlayerID: game.CurrentPlayerID,
-		GamePlayers:     ConvertGamePlayersToResponse(game.Players),
-		WinFactor:       game.OperatorIdentifier.WinFactor,
+// Errors that are not a *models.MoveError are sent with the ILLEGAL_MOVE code.
+func GenerateInvalidMoveMessage(game models.Game, move models.Move, err error) ([]byte, error) {
+	moveErr := models.NewMoveError(models.ErrIllegalMove, "%v", err)
+	errors.As(err, &moveErr)
+	invalidMove := InvalidMove{
+		Code:       moveErr.Code,
+		Reason:     moveErr.Message,
+		Move:       move,
+		BoardState: newGameStartMessage(game),
 	}
+	return NewMessage("invalid_move", invalidMove)
+}
+
+func GenerateGameReconnectMessage(game models.Game) ([]byte, error) {
+	gamestart := newGameStartMessage(game)
 	return NewMessage("game_reconnect", gamestart)
 }
 
@@ -264,18 +301,23 @@ func GenerateGameTimerMessage(game models.Game, timer int) ([]byte, error) {
 	return NewMessage("game_timer", gamestart)
 }
 
+// For a draw there is no winner, winnings holds the amount refunded to each player.
 func GenerateGameOverMessage(reason string, game models.Game, winnings int64) ([]byte, error) {
-	winner, err := game.GetGamePlayer(game.Winner)
-	if err != nil {
-		log.Printf("Error retrieving game winner player: %v\n", err)
-	}
-
 	gameover := GameOver{
 		Reason:   reason,
-		Winner:   ConvertGamePlayerToResponse(*winner),
 		Turns:    game.Turn,
 		GameTime: game.EndTime.Sub(game.StartTime),
 		Winnings: float64(winnings) / 100.0,
+		IsDraw:   game.IsDraw(),
+		DrawRule: game.DrawRule,
+	}
+	if game.Winner != "" {
+		winner, err := game.GetGamePlayer(game.Winner)
+		if err != nil {
+			log.Printf("Error retrieving game winner player: %v\n", err)
+			return nil, err
+		}
+		gameover.Winner = ConvertGamePlayerToResponse(*winner)
 	}
 	return NewMessage("game_over", gameover)
 }
@@ -284,6 +326,10 @@ func GenerateMoveMessage(move models.Move) ([]byte, error) {
 	return NewMessage("move_piece", move)
 }
 
+func GenerateMoveResultMessage(result models.MoveResult) ([]byte, error) {
+	return NewMessage("move_result", result)
+}
+
 // Helper function to marshal a value and ignore errors
 func MustMarshal(v interface{}) json.RawMessage {
 	bytes, _ := json.Marshal(v)
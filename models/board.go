@@ -3,40 +3,45 @@ package models
 import (
 	"fmt"
 	"log"
+	"strconv"
 
 	"github.com/google/uuid"
 )
 
 type Board struct {
-	Grid map[string]*Piece
+	Grid    map[string]*Piece
+	Variant string `json:"variant,omitempty"` // Rules the board is played with, see RuleSets.
+	// Squares of the pieces taken by the capture sequence in progress, with the RemoveCapturedAtEnd
+	// rule they stay on the board until the sequence ends.
+	Captured []string `json:"captured,omitempty"`
 }
 
-func NewBoard(blackID, whiteID, boardtype string) *Board {
-	board := &Board{Grid: make(map[string]*Piece)}
-	switch boardtype {
-	case "std-game":
-		board.GenerateInitialBoard(blackID, whiteID) // Automatically initialize board state
-	case "two-pieces-endgame":
-		board.GenerateEndGameTestBoard(blackID, whiteID) // Automatically initialize board state
-	case "multiple-capture":
-		board.GenerateMultipleCaptureTestBoard(blackID, whiteID) // Automatically initialize board state
-	}
+// NewBoard returns the starting position of the variant, use ParseFEN to start from any other position.
+func NewBoard(blackID, whiteID, variant string) *Board {
+	board := &Board{Grid: make(map[string]*Piece), Variant: GetRuleSet(variant).Name}
+	board.GenerateInitialBoard(blackID, whiteID) // Automatically initialize board state
 	return board
 }
 
+// Rules returns the RuleSet of the board variant.
+func (b *Board) Rules() RuleSet {
+	return GetRuleSet(b.Variant)
+}
+
 // GenerateInitialBoard initializes the board with starting pieces
 func (b *Board) GenerateInitialBoard(blackID, whiteID string) {
-	rows := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+	size := b.Rules().BoardSize
+	pieceRows := b.Rules().PieceRows()
 
-	for i, row := range rows {
-		for col := 1; col <= 8; col++ {
-			pos := fmt.Sprintf("%s%d", row, col)
+	for i := 0; i < size; i++ {
+		for col := 1; col <= size; col++ {
+			pos := squareName('A'+rune(i), col)
 			b.Grid[pos] = nil
 			// Only place pieces on dark squares
 			if (i+col)%2 == 1 {
-				if i < 3 { // Top 3 rows for black pieces
+				if i < pieceRows { // Top rows for black pieces
 					b.Grid[pos] = &Piece{Type: "b", PieceID: uuid.New().String(), PlayerID: blackID}
-				} else if i > 4 { // Bottom 3 rows for white pieces
+				} else if i >= size-pieceRows { // Bottom rows for white pieces
 					b.Grid[pos] = &Piece{Type: "w", PieceID: uuid.New().String(), PlayerID: whiteID}
 				} else {
 					b.Grid[pos] = nil // Empty middle rows
@@ -46,52 +51,22 @@ func (b *Board) GenerateInitialBoard(blackID, whiteID string) {
 	}
 }
 
-// GenerateEndGameTestBoard initializes the board with a test configuration
-func (b *Board) GenerateEndGameTestBoard(blackID, whiteID string) {
-	// Set positions for testing
-	testPositions := map[string]*Piece{
-		"A2": {Type: "b", PieceID: uuid.New().String(), PlayerID: blackID}, // Black piece
-		"B3": {Type: "w", PieceID: uuid.New().String(), PlayerID: whiteID}, // White piece
-	}
-
-	// Initialize board and place test pieces
-	for row := 'A'; row <= 'H'; row++ {
-		for col := 1; col <= 8; col++ {
-			pos := fmt.Sprintf("%c%d", row, col)
-			if piece, exists := testPositions[pos]; exists {
-				b.Grid[pos] = piece // Place test pieces
-			} else {
-				b.Grid[pos] = nil // Empty squares
-			}
+// generateEmptyBoard fills the board with empty squares.
+func (b *Board) generateEmptyBoard() {
+	size := b.Rules().BoardSize
+	for row := 'A'; row < 'A'+rune(size); row++ {
+		for col := 1; col <= size; col++ {
+			b.Grid[squareName(row, col)] = nil
 		}
 	}
 }
 
-// Test config for multiple capture
-func (b *Board) GenerateMultipleCaptureTestBoard(blackID, whiteID string) {
-	// Set positions for testing
-	testPositions := map[string]*Piece{
-		"A1": {Type: "b", PieceID: uuid.New().String(), PlayerID: blackID},
-		"A3": {Type: "b", PieceID: uuid.New().String(), PlayerID: blackID},
-		"A7": {Type: "b", PieceID: uuid.New().String(), PlayerID: blackID},
-		"B2": {Type: "b", PieceID: uuid.New().String(), PlayerID: blackID},
-		"B4": {Type: "b", PieceID: uuid.New().String(), PlayerID: blackID},
-		"C5": {Type: "b", PieceID: uuid.New().String(), PlayerID: blackID},
-		"D6": {Type: "w", PieceID: uuid.New().String(), PlayerID: whiteID},
-		"E7": {Type: "w", PieceID: uuid.New().String(), PlayerID: whiteID},
-	}
-
-	// Initialize board and place test pieces
-	for row := 'A'; row <= 'H'; row++ {
-		for col := 1; col <= 8; col++ {
-			pos := fmt.Sprintf("%c%d", row, col)
-			if piece, exists := testPositions[pos]; exists {
-				b.Grid[pos] = piece // Place test pieces
-			} else {
-				b.Grid[pos] = nil // Empty squares
-			}
-		}
+// RemoveCaptured takes the pieces captured by the sequence in progress off the board.
+func (b *Board) RemoveCaptured() {
+	for _, pos := range b.Captured {
+		b.Grid[pos] = nil
 	}
+	b.Captured = nil
 }
 
 func (b *Board) GetPieceByID(pieceID string) *Piece {
@@ -106,88 +81,39 @@ func (b *Board) GetPieceByID(pieceID string) *Piece {
 	return nil
 }
 
-func (b *Board) CanPieceCaptureNEW(pos string) bool {
-	piece, exists := b.Grid[pos]
-	if !exists || piece == nil {
-		log.Printf("(CanPieceCapture) - Piece doesn't exist on the board\n")
-		return false // No piece at this position
-	}
-
-	// Define the possible capture directions
-	var directions []struct{ rowDelta, colDelta int }
-
-	if piece.IsKinged {
-		// Kings can move in all four diagonal directions
-		directions = []struct{ rowDelta, colDelta int }{
-			{1, 1},   // Diagonal right (down)
-			{1, -1},  // Diagonal left (down)
-			{-1, 1},  // Diagonal right (up)
-			{-1, -1}, // Diagonal left (up)
-		}
-		//log.Printf("(CanPieceCapture) - Piece is king, multiple directions.")
-	} else {
-		//log.Printf("(CanPieceCapture) - Piece is not king, single direction.")
-		// Normal pieces can only move forward
-		var direction = GetPieceDirection(*piece)
-		directions = []struct{ rowDelta, colDelta int }{
-			{direction, 1},  // Diagonal right
-			{direction, -1}, // Diagonal left
-		}
-	}
-
-	// Convert position (e.g., "A3" → row 'A', col 3)
-	fromRow, fromCol, err := parsePosition(pos)
-	if err != nil {
-		log.Println(err)
-		return false
-	}
-
-	for _, dir := range directions {
-		// Compute middle position (opponent's piece)
-		midRow := fromRow + rune(dir.rowDelta)
-		midCol := fromCol + dir.colDelta
-		midPos := fmt.Sprintf("%c%d", midRow, midCol)
-
-		// Compute landing position
-		landRow := fromRow + rune(2*dir.rowDelta)
-		landCol := fromCol + 2*dir.colDelta
-		landPos := fmt.Sprintf("%c%d", landRow, landCol)
-		//log.Printf("(CanPieceCapture) - Checking landPos [%s]\n", landPos)
-		// Ensure middle square has an opponent piece
-		midPiece, midExists := b.Grid[midPos]
-		if !midExists || midPiece == nil || midPiece.PlayerID == piece.PlayerID {
-			//log.Printf("(CanPieceCapture) - Middle piece doesn't exist or is not an opponent!\n")
-			continue // No opponent to jump over
-		}
-		//log.Printf("(CanPieceCapture) - Middle piece exists and is an opponent!\n")
-		// Ensure landing square is empty
-		if destPiece, destExists := b.Grid[landPos]; destExists && destPiece == nil {
-			//log.Printf("(CanPieceCapture) - Valid capture move found\n")
-			return true // Valid capture move found!
-		}
-	}
-	//log.Printf("(CanPieceCapture) - No captures available\n")
-	return false // No captures available
+// FinishHop promotes the piece that just moved to pos when due, and reports if the turn continues
+// with another capture by the same piece.
+//
+// A man ending its move on the last row is promoted. When it lands there in the middle of a capture
+// sequence it keeps capturing as a man, unless the rules promote it mid capture.
+func (b *Board) FinishHop(pos string, wasCapture bool) (kinged bool, turnContinues bool) {
+	piece := b.Grid[pos]
+	if piece == nil {
+		return false, false
+	}
+	turnContinues = wasCapture && b.CanPieceCapture(pos)
+	if piece.IsKinged || !b.isPromotionSquare(pos, *piece) {
+		return false, turnContinues
+	}
+	rules := b.Rules()
+	if turnContinues && !rules.PromoteMidCapture {
+		return false, true
+	}
+	piece.IsKinged = true
+	log.Printf("(FinishHop) - Piece %s was kinged on %s!", piece.PieceID, pos)
+	if wasCapture && rules.PromoteMidCapture {
+		return true, b.CanPieceCapture(pos)
+	}
+	return true, false
 }
 
-func (b *Board) WasPieceKinged(pos string, piece Piece) bool {
-	if piece.IsKinged { // If its alerady a king we just return false.
-		return false
-	}
-
-	if len(pos) == 0 {
+// isPromotionSquare reports if a man of the piece's colour is promoted on pos.
+func (b *Board) isPromotionSquare(pos string, piece Piece) bool {
+	row, _, err := parsePosition(pos)
+	if err != nil {
 		return false
 	}
-	firstChar := pos[0]
-	if piece.Type == "b" && firstChar == 'H' {
-		log.Printf("(WasPieceKinged) - Black piece was kinged!")
-		return true
-	}
-	if piece.Type == "w" && firstChar == 'A' {
-		log.Printf("(WasPieceKinged) - White piece was kinged!")
-		return true
-	}
-	return false
+	return (piece.Type == "b" && row == b.Rules().LastRow()) || (piece.Type == "w" && row == 'A')
 }
 
 func GetPieceDirection(piece Piece) int {
@@ -198,169 +124,26 @@ func GetPieceDirection(piece Piece) int {
 	}
 }
 
-func (b *Board) PiecesThatCanCapture(playerID string) []Piece {
-	var capturers []Piece
-	for pos, piece := range b.Grid {
-		if piece == nil {
-			continue
-		}
-		if piece.PlayerID != playerID {
-			continue
-		}
-		if b.CanPieceCaptureNEW(pos) {
-			capturers = append(capturers, *piece)
-		}
-	}
-	return capturers
-}
-
-// parsePosition converts a position string (e.g., "A3") into row (rune) and column (int).
-// Returns an error if the position is invalid.
+// parsePosition converts a position string (e.g., "A3" or "J10") into row (rune) and column (int).
+// Returns an error if the position is invalid, squares outside of the board variant are checked against the Grid.
 func parsePosition(pos string) (rune, int, error) {
-	if len(pos) != 2 {
-		return 0, 0, fmt.Errorf("(Parse Position) - invalid position format: must be 2 characters (e.g., 'A3')")
-	}
-	row := rune(pos[0])      // Convert the first character to a rune (e.g., 'A')
-	col := int(pos[1] - '0') // Convert the second character to an integer (e.g., '3' → 3)
-
-	// Validate the row and column
-	if row < 'A' || row > 'H' || col < 1 || col > 8 {
-		return 0, 0, fmt.Errorf("(Parse Position) - position is out of bounds: must be between A1 and H8")
-	}
-	return row, col, nil
-}
-
-func (b *Board) IsValidMove(move Move) (bool, error) {
-	piece, exists := b.Grid[move.From]
-	if !exists || piece == nil {
-		return false, fmt.Errorf("(isValidMove) - piece does not exist at the source square")
-	}
-	// Check if the piece belongs to the player making the move
-	if piece.PlayerID != move.PlayerID {
-		return false, fmt.Errorf("(isValidMove) - piece does not belong to the player")
-	}
-	fromRow, fromCol, err := parsePosition(move.From)
-	if err != nil {
-		return false, fmt.Errorf("(isValidMove) - invalid source position: %v", err)
+	if len(pos) < 2 || len(pos) > 3 {
+		return 0, 0, fmt.Errorf("(Parse Position) - invalid position format: must be 2 or 3 characters (e.g., 'A3', 'J10')")
 	}
-	toRow, toCol, err := parsePosition(move.To)
+	row := rune(pos[0])               // Convert the first character to a rune (e.g., 'A')
+	col, err := strconv.Atoi(pos[1:]) // Convert the remaining characters to an integer (e.g., '3' → 3)
 	if err != nil {
-		return false, fmt.Errorf("(isValidMove) - invalid destination position: %v", err)
+		return 0, 0, fmt.Errorf("(Parse Position) - invalid column: %v", err)
 	}
-	// Check if the destination square is empty
-	_, exists = b.Grid[move.To]
-	if !exists {
-		return false, fmt.Errorf("(isValidMove) - destination square doesn't exist")
-	}
-	if b.Grid[move.To] != nil {
-		return false, fmt.Errorf("(isValidMove) - destination square is not empty")
-	}
-	// Calculate the difference in rows and columns
-	deltaRow := int(toRow - fromRow)
-	deltaCol := toCol - fromCol
-	// Skip direction validation if the piece is kinged
-	if !piece.IsKinged {
-		direction := GetPieceDirection(*piece)
-		if deltaRow*direction <= 0 { // Check if the move is in the correct direction
-			return false, fmt.Errorf("(isValidMove) - move is not in the correct direction for the piece type")
-		}
-	}
-	// Check if the move is diagonal
-	if abs(deltaCol) != 1 || abs(deltaRow) != 1 {
-		// If it's not a single diagonal move, check if it's a capture
-		if !move.IsCapture || abs(deltaCol) != 2 || abs(deltaRow) != 2 {
-			return false, fmt.Errorf("(isValidMove) - move is not diagonal or a valid capture")
-		}
 
-		// For a capture, check if the intermediate square has an opponent's piece
-		captureRow := fromRow + rune(deltaRow/2)
-		captureCol := fromCol + deltaCol/2
-		captureSquare := string(captureRow) + string('0'+captureCol)
-		capturedPiece, exists := b.Grid[captureSquare]
-		if !exists || capturedPiece == nil || capturedPiece.PlayerID == move.PlayerID {
-			return false, fmt.Errorf("(isValidMove) - invalid capture: no opponent's piece to capture")
-		}
-	}
-	// If all checks pass, the move is valid
-	return true, nil
-}
-
-func (b *Board) IsValidMoveKing(move Move) (bool, error) {
-	piece, exists := b.Grid[move.From]
-	if !exists || piece == nil {
-		return false, fmt.Errorf("(IsValidMoveKing) - piece does not exist at source")
-	}
-	if piece.PlayerID != move.PlayerID {
-		return false, fmt.Errorf("(IsValidMoveKing) - piece does not belong to player")
-	}
-	if !piece.IsKinged {
-		return false, fmt.Errorf("(IsValidMoveKing) - piece is not kinged")
-	}
-
-	fromRow, fromCol, err := parsePosition(move.From)
-	if err != nil {
-		return false, fmt.Errorf("(IsValidMoveKing) - invalid source: %v", err)
-	}
-	toRow, toCol, err := parsePosition(move.To)
-	if err != nil {
-		return false, fmt.Errorf("(IsValidMoveKing) - invalid destination: %v", err)
-	}
-
-	if _, ok := b.Grid[move.To]; !ok {
-		return false, fmt.Errorf("(IsValidMoveKing) - destination does not exist")
-	}
-	if b.Grid[move.To] != nil {
-		return false, fmt.Errorf("(IsValidMoveKing) - destination not empty")
-	}
-
-	deltaRow := int(toRow - fromRow)
-	deltaCol := int(toCol - fromCol)
-
-	if abs(deltaRow) != abs(deltaCol) {
-		return false, fmt.Errorf("(IsValidMoveKing) - move not diagonal")
-	}
-
-	stepRow := 1
-	if deltaRow < 0 {
-		stepRow = -1
-	}
-	stepCol := 1
-	if deltaCol < 0 {
-		stepCol = -1
-	}
-
-	enemySeen := false
-	for r, c := fromRow+rune(stepRow), fromCol+stepCol; r != toRow && c != toCol; r, c = r+rune(stepRow), c+stepCol {
-		square := string(r) + string('0'+c)
-		p, exists := b.Grid[square]
-		if !exists {
-			return false, fmt.Errorf("(IsValidMoveKing) - square %v does not exist", square)
-		}
-		if p == nil {
-			continue
-		}
-		if p.PlayerID == move.PlayerID {
-			return false, fmt.Errorf("(IsValidMoveKing) - path blocked by own piece at %v", square)
-		}
-		if enemySeen {
-			return false, fmt.Errorf("(IsValidMoveKing) - multiple captures not supported in one move")
-		}
-		enemySeen = true
-	}
-
-	if enemySeen && !move.IsCapture {
-		return false, fmt.Errorf("(IsValidMoveKing) - move is a capture but not flagged as capture")
-	}
-	if !enemySeen && move.IsCapture {
-		return false, fmt.Errorf("(IsValidMoveKing) - flagged as capture but no enemy on path")
+	// Validate the row and column
+	if row < 'A' || row >= 'A'+MaxBoardSize || col < 1 || col > MaxBoardSize {
+		return 0, 0, fmt.Errorf("(Parse Position) - position is out of bounds: must be between A1 and J10")
 	}
-
-	return true, nil
+	return row, col, nil
 }
 
-func abs(n int) int {
-	if n < 0 {
-		return -n
-	}
-	return n
+// squareName builds the position string for a row and column, e.g. ('A', 3) → "A3".
+func squareName(row rune, col int) string {
+	return fmt.Sprintf("%c%d", row, col)
 }
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// DeadLetter is a queue message a worker could not handle, kept until it is replayed or purged.
+type DeadLetter struct {
+	ID         string    `json:"id"`
+	Queue      string    `json:"queue"`
+	Worker     string    `json:"worker"`   // Consumer group of the worker, e.g. GameWorker.
+	Consumer   string    `json:"consumer"` // Process of the worker that gave up on the message.
+	MessageID  string    `json:"message_id"`
+	Payload    string    `json:"payload"`
+	Error      string    `json:"error"`
+	Deliveries int64     `json:"deliveries"`
+	DeadAt     time.Time `json:"dead_at"`
+}
+
+// RedactedValue is the placeholder of the payload values that are not shown.
+const RedactedValue = "[redacted]"
+
+// redactedFields are the credentials of the players carried by the queue messages.
+var redactedFields = map[string]bool{"token": true, "session_id": true}
+
+// Redacted returns the dead letter with the player tokens and session IDs of its payload, at any depth,
+// replaced by RedactedValue. A payload that is not JSON is replaced as a whole.
+func (d DeadLetter) Redacted() DeadLetter {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(d.Payload)))
+	decoder.UseNumber()
+	var payload any
+	if err := decoder.Decode(&payload); err != nil {
+		d.Payload = RedactedValue
+		return d
+	}
+	data, err := json.Marshal(redactFields(payload))
+	if err != nil {
+		d.Payload = RedactedValue
+		return d
+	}
+	d.Payload = string(data)
+	return d
+}
+
+func redactFields(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if redactedFields[key] {
+				v[key] = RedactedValue
+			} else {
+				v[key] = redactFields(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactFields(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDeadLetterRedacted(t *testing.T) {
+	command, err := json.Marshal(GameCommand{
+		Type:   "leave_game",
+		GameID: "game",
+		Data:   json.RawMessage(`{"id":"p1","token":"jwt","session_id":"s1","name":"Ana","bet":0.5}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadLetter := DeadLetter{ID: "1-0", Queue: "game_commands:3", Payload: string(command)}
+
+	redacted := deadLetter.Redacted()
+	for _, secret := range []string{"jwt", `"s1"`} {
+		if strings.Contains(redacted.Payload, secret) {
+			t.Errorf("redacted payload %s still has %s", redacted.Payload, secret)
+		}
+	}
+	for _, kept := range []string{`"name":"Ana"`, `"bet":0.5`, `"game_id":"game"`} {
+		if !strings.Contains(redacted.Payload, kept) {
+			t.Errorf("redacted payload %s lost %s", redacted.Payload, kept)
+		}
+	}
+	if deadLetter.Payload != string(command) {
+		t.Error("Redacted() changed the payload of the dead letter")
+	}
+
+	if got := (DeadLetter{Payload: "token=jwt"}).Redacted().Payload; got != RedactedValue {
+		t.Errorf("payload that is not JSON redacted to %q, want %q", got, RedactedValue)
+	}
+}
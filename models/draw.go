@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DrawRules holds the configurable conditions that end a game in a draw.
+// A zero value on any of the limits disables that rule.
+type DrawRules struct {
+	Repetitions          int  // Times the same position must be reached, with the same side to move.
+	MovesWithoutProgress int  // Moves in a row without a capture or a man moving.
+	KingVsKing           bool // A single king against a single king.
+}
+
+// PositionKey identifies the pieces on the board and the side to move, to detect repetitions.
+func (b *Board) PositionKey(sideToMoveID string) string {
+	var squares []string
+	for pos, piece := range b.Grid {
+		if piece == nil {
+			continue
+		}
+		pieceType := piece.Type
+		if piece.IsKinged {
+			pieceType = strings.ToUpper(pieceType)
+		}
+		squares = append(squares, pos+pieceType)
+	}
+	sort.Strings(squares)
+	return fmt.Sprintf("%s|%s", strings.Join(squares, ","), sideToMoveID)
+}
+
+// RecordMoveProgress updates the moves without progress counter.
+// A capture or a man moving can't be undone, so earlier positions can't repeat and are dropped.
+func (g *Game) RecordMoveProgress(move Move, movedMan bool) {
+	if move.IsCapture || movedMan {
+		g.MovesWithoutProgress = 0
+		g.PositionCounts = map[string]int{}
+		return
+	}
+	g.MovesWithoutProgress++
+}
+
+// RecordPosition counts the current position, it should be called when a turn ends.
+func (g *Game) RecordPosition(sideToMoveID string) {
+	if g.PositionCounts == nil {
+		g.PositionCounts = map[string]int{}
+	}
+	g.PositionCounts[g.Board.PositionKey(sideToMoveID)]++
+}
+
+// CheckDraw evaluates the draw rules against the current position.
+// Returns if the game is a draw and which rule caused it.
+func (g *Game) CheckDraw(rules DrawRules, sideToMoveID string) (bool, string) {
+	if rules.KingVsKing && g.isKingVsKing() {
+		return true, "king_vs_king"
+	}
+	if rules.Repetitions > 0 && g.PositionCounts[g.Board.PositionKey(sideToMoveID)] >= rules.Repetitions {
+		return true, "repetition"
+	}
+	if rules.MovesWithoutProgress > 0 && g.MovesWithoutProgress >= rules.MovesWithoutProgress {
+		return true, "move_limit"
+	}
+	return false, ""
+}
+
+func (g *Game) isKingVsKing() bool {
+	kings := map[string]int{}
+	for _, piece := range g.Board.Grid {
+		if piece == nil {
+			continue
+		}
+		if !piece.IsKinged {
+			return false
+		}
+		kings[piece.PlayerID]++
+	}
+	if len(kings) != 2 {
+		return false
+	}
+	for _, count := range kings {
+		if count != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDraw reports if the game ended in a draw, finished games without a winner that were not ended by
+// a draw rule or an agreement are not draws, their bets are not refunded.
+func (g *Game) IsDraw() bool {
+	return !g.EndTime.IsZero() && g.DrawRule != ""
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// testGame starts a game between testBlackID and testWhiteID on the board of testBoard.
+func testGame(t *testing.T, fen, variant string) *Game {
+	t.Helper()
+	board, currentPlayerID := testBoard(t, fen, variant)
+	game := &Game{
+		ID:    "game",
+		Board: *board,
+		Players: []GamePlayer{
+			{ID: testBlackID, Name: "Black", Color: "b"},
+			{ID: testWhiteID, Name: "White", Color: "w"},
+		},
+		CurrentPlayerID: currentPlayerID,
+		Moves:           []Move{},
+		PositionCounts:  map[string]int{},
+		StartTime:       time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Variant:         board.Variant,
+	}
+	game.UpdatePlayerPieces()
+	return game
+}
+
+func TestCheckDraw(t *testing.T) {
+	allRules := DrawRules{Repetitions: 3, MovesWithoutProgress: 4, KingVsKing: true}
+	tests := []struct {
+		name     string
+		fen      string
+		rules    DrawRules
+		repeated int // Times the position was already reached.
+		progress int // Moves without progress so far.
+		wantRule string
+	}{
+		{name: "king against king", fen: "B:WK32:BK1", rules: allRules, wantRule: "king_vs_king"},
+		{name: "king against king disabled", fen: "B:WK32:BK1", rules: DrawRules{}},
+		{name: "king and man against king", fen: "B:WK32:BK1,5", rules: allRules},
+		{name: "repeated position", fen: "B:WK32:BK1,K2", rules: allRules, repeated: 3, wantRule: "repetition"},
+		{name: "position reached twice", fen: "B:WK32:BK1,K2", rules: allRules, repeated: 2},
+		{name: "move limit", fen: "B:WK32:BK1,K2", rules: allRules, progress: 4, wantRule: "move_limit"},
+		{name: "move limit disabled", fen: "B:WK32:BK1,K2", rules: DrawRules{Repetitions: 3}, progress: 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := testGame(t, tt.fen, "classic")
+			game.MovesWithoutProgress = tt.progress
+			for i := 0; i < tt.repeated; i++ {
+				game.RecordPosition(testBlackID)
+			}
+			isDraw, rule := game.CheckDraw(tt.rules, testBlackID)
+			if isDraw != (tt.wantRule != "") || rule != tt.wantRule {
+				t.Errorf("CheckDraw() = %v, %q, want %q", isDraw, rule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestRecordMoveProgress(t *testing.T) {
+	game := testGame(t, "B:WK32:BK1,5", "classic")
+	game.RecordPosition(testBlackID)
+	game.RecordMoveProgress(Move{From: "A1", To: "B2"}, false)
+	if game.MovesWithoutProgress != 1 || len(game.PositionCounts) != 1 {
+		t.Fatalf("after a king move MovesWithoutProgress = %d, positions = %d, want 1 and 1", game.MovesWithoutProgress, len(game.PositionCounts))
+	}
+	game.RecordMoveProgress(Move{From: "B2", To: "C3"}, true)
+	if game.MovesWithoutProgress != 0 || len(game.PositionCounts) != 0 {
+		t.Errorf("after a man move MovesWithoutProgress = %d, positions = %d, want 0 and 0", game.MovesWithoutProgress, len(game.PositionCounts))
+	}
+}
+
+func TestIsDraw(t *testing.T) {
+	game := testGame(t, "", "classic")
+	if game.IsDraw() {
+		t.Fatal("IsDraw() = true for a game in progress")
+	}
+	game.FinishGame("")
+	if game.IsDraw() {
+		t.Error("IsDraw() = true for a game that ended without a winner or a draw rule")
+	}
+	if got := game.pdnResult(); got != PDNUnknown {
+		t.Errorf("pdnResult() = %s for a game without a result, want %s", got, PDNUnknown)
+	}
+
+	game = testGame(t, "", "classic")
+	game.FinishGameAsDraw("agreement")
+	if !game.IsDraw() {
+		t.Error("IsDraw() = false after FinishGameAsDraw")
+	}
+	if got := game.pdnResult(); got != PDNDraw {
+		t.Errorf("pdnResult() = %s for a draw, want %s", got, PDNDraw)
+	}
+
+	game = testGame(t, "", "classic")
+	game.FinishGame(testWhiteID)
+	if game.IsDraw() {
+		t.Error("IsDraw() = true for a game with a winner")
+	}
+	if got := game.pdnResult(); got != PDNWhiteWins {
+		t.Errorf("pdnResult() = %s for a white win, want %s", got, PDNWhiteWins)
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import "fmt"
+
+// DrawCommand is pushed by the ws api to the gameworker for the offer_draw, accept_draw and decline_draw commands.
+type DrawCommand struct {
+	PlayerID string `json:"player_id"`
+	Command  string `json:"command"`
+}
+
+// DrawOffer is a pending draw offer, it expires when the turn changes.
+type DrawOffer struct {
+	PlayerID string `json:"player_id"`
+	Turn     int    `json:"turn"`
+}
+
+// OfferDraw registers a draw offer from the player.
+// Only one offer can be pending and each player can make up to maxOffers offers per game, 0 means no limit.
+func (g *Game) OfferDraw(playerID string, maxOffers int) error {
+	if _, err := g.GetGamePlayer(playerID); err != nil {
+		return err
+	}
+	if g.DrawOffer != nil {
+		return fmt.Errorf("there is already a pending draw offer")
+	}
+	if maxOffers > 0 && g.DrawOffersMade[playerID] >= maxOffers {
+		return fmt.Errorf("draw offer limit of %d reached", maxOffers)
+	}
+	if g.DrawOffersMade == nil {
+		g.DrawOffersMade = map[string]int{}
+	}
+	g.DrawOffersMade[playerID]++
+	g.DrawOffer = &DrawOffer{PlayerID: playerID, Turn: g.Turn}
+	return nil
+}
+
+// AcceptDraw accepts the pending offer, only the opponent of the player who offered can accept it.
+func (g *Game) AcceptDraw(playerID string) error {
+	if err := g.checkDrawOfferAnswer(playerID); err != nil {
+		return err
+	}
+	g.DrawOffer = nil
+	return nil
+}
+
+// DeclineDraw declines the pending offer and returns the ID of the player who made it.
+func (g *Game) DeclineDraw(playerID string) (string, error) {
+	if err := g.checkDrawOfferAnswer(playerID); err != nil {
+		return "", err
+	}
+	offeredBy := g.DrawOffer.PlayerID
+	g.DrawOffer = nil
+	return offeredBy, nil
+}
+
+func (g *Game) checkDrawOfferAnswer(playerID string) error {
+	if g.DrawOffer == nil || g.DrawOffer.Turn != g.Turn {
+		return fmt.Errorf("there is no pending draw offer")
+	}
+	if g.DrawOffer.PlayerID == playerID {
+		return fmt.Errorf("can't answer your own draw offer")
+	}
+	return nil
+}
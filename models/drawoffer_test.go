@@ -0,0 +1,130 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// playDrawStep plays a step of the draw offer flow, e.g. "black offers", "white accepts", "white
+// declines" or "turn ends".
+func playDrawStep(game *Game, step string, maxOffers int) error {
+	playerID, action, _ := strings.Cut(step, " ")
+	switch action {
+	case "offers":
+		return game.OfferDraw(playerID, maxOffers)
+	case "accepts":
+		return game.AcceptDraw(playerID)
+	case "declines":
+		_, err := game.DeclineDraw(playerID)
+		return err
+	default:
+		game.NextPlayer()
+		return nil
+	}
+}
+
+func TestDrawOffer(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxOffers int
+		steps     []string
+		wantErr   string // Error of the last step, empty when it succeeds.
+		wantOffer string // Player of the pending offer after the steps.
+	}{
+		{
+			name:      "offer",
+			steps:     []string{"black offers"},
+			wantOffer: testBlackID,
+		},
+		{
+			name:  "accept",
+			steps: []string{"black offers", "white accepts"},
+		},
+		{
+			name:  "decline",
+			steps: []string{"black offers", "white declines"},
+		},
+		{
+			name:    "accept without an offer",
+			steps:   []string{"white accepts"},
+			wantErr: "no pending draw offer",
+		},
+		{
+			name:      "own offer can't be answered",
+			steps:     []string{"black offers", "black accepts"},
+			wantErr:   "can't answer your own draw offer",
+			wantOffer: testBlackID,
+		},
+		{
+			name:      "one offer pending at a time",
+			steps:     []string{"black offers", "white offers"},
+			wantErr:   "already a pending draw offer",
+			wantOffer: testBlackID,
+		},
+		{
+			name:    "offer expires on turn change",
+			steps:   []string{"black offers", "turn ends", "white accepts"},
+			wantErr: "no pending draw offer",
+		},
+		{
+			name:    "decline after the offer expired",
+			steps:   []string{"black offers", "turn ends", "white declines"},
+			wantErr: "no pending draw offer",
+		},
+		{
+			name:      "offer limit",
+			maxOffers: 1,
+			steps:     []string{"black offers", "white declines", "black offers"},
+			wantErr:   "draw offer limit of 1 reached",
+		},
+		{
+			name:      "offer limit is per player",
+			maxOffers: 1,
+			steps:     []string{"black offers", "white declines", "white offers"},
+			wantOffer: testWhiteID,
+		},
+		{
+			name:      "no limit",
+			steps:     []string{"black offers", "white declines", "black offers", "white declines", "black offers"},
+			wantOffer: testBlackID,
+		},
+		{
+			name:    "players of other games can't offer",
+			steps:   []string{"nobody offers"},
+			wantErr: "not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := testGame(t, "", "classic")
+			var err error
+			for i, step := range tt.steps {
+				err = playDrawStep(game, step, tt.maxOffers)
+				if err != nil && i < len(tt.steps)-1 {
+					t.Fatalf("%s: %v", step, err)
+				}
+			}
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("%s: %v", tt.steps[len(tt.steps)-1], err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("%s = %v, want an error with %q", tt.steps[len(tt.steps)-1], err, tt.wantErr)
+			}
+			offeredBy := ""
+			if game.DrawOffer != nil {
+				offeredBy = game.DrawOffer.PlayerID
+			}
+			if offeredBy != tt.wantOffer {
+				t.Errorf("pending offer of %q, want %q", offeredBy, tt.wantOffer)
+			}
+		})
+	}
+}
+
+func TestDeclineDrawReturnsTheOfferingPlayer(t *testing.T) {
+	game := testGame(t, "", "classic")
+	game.OfferDraw(testBlackID, 0)
+	if offeredBy, err := game.DeclineDraw(testWhiteID); err != nil || offeredBy != testBlackID {
+		t.Errorf("DeclineDraw() = %q, %v, want %q", offeredBy, err, testBlackID)
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Test positions for the gameworker start_fen setting, black to move.
+const (
+	EndGameTestFEN         = "B:W5:B1"                // A black man that captures the last white man.
+	MultipleCaptureTestFEN = "B:W15,23:B1,2,4,5,6,11" // A black man on C5 with a double capture.
+)
+
+// FEN writes the board as a draughts FEN, e.g. "B:W21,22,K30:B1,2,K12".
+//
+// Squares use the same numbers as the PDN export, kings are prefixed with K. In the middle of a capture
+// sequence with the RemoveCapturedAtEnd rule, the pieces taken but still on the board (Board.Captured)
+// are prefixed with X, e.g. "W:W21,X22:B1", so the position round-trips through ParseFEN.
+func (b *Board) FEN(sideToMove Side) string {
+	p := b.Position()
+	var black, white []string
+	for sq := range p.geometry().names {
+		bit := uint64(1) << sq
+		square := strconv.Itoa(sq + 1)
+		if p.Kings&bit != 0 {
+			square = "K" + square
+		}
+		if p.Taken&bit != 0 {
+			square = "X" + square
+		}
+		switch {
+		case p.Black&bit != 0:
+			black = append(black, square)
+		case p.White&bit != 0:
+			white = append(white, square)
+		}
+	}
+	return fmt.Sprintf("%s:W%s:B%s", sideToMove, strings.Join(white, ","), strings.Join(black, ","))
+}
+
+// String returns the FEN letter of the side, "B" or "W".
+func (s Side) String() string {
+	if s == SideWhite {
+		return "W"
+	}
+	return "B"
+}
+
+// ParseFEN builds a board of the variant from a draughts FEN and returns the side to move.
+//
+// Piece lists can hold ranges like "1-12", the order of the W and B lists does not matter. Pieces
+// prefixed with X are taken by the capture sequence in progress, see Board.FEN.
+func ParseFEN(fen, blackID, whiteID, variant string) (*Board, Side, error) {
+	board := &Board{Grid: make(map[string]*Piece), Variant: GetRuleSet(variant).Name}
+	board.generateEmptyBoard()
+	position := board.Position()
+
+	fields := strings.Split(strings.TrimSuffix(strings.TrimSpace(fen), "."), ":")
+	if len(fields) != 3 {
+		return nil, SideBlack, fmt.Errorf("(ParseFEN) - invalid FEN %q: expected side:pieces:pieces", fen)
+	}
+	var sideToMove Side
+	switch fields[0] {
+	case "B":
+		sideToMove = SideBlack
+	case "W":
+		sideToMove = SideWhite
+	default:
+		return nil, SideBlack, fmt.Errorf("(ParseFEN) - invalid side to move %q", fields[0])
+	}
+
+	for _, field := range fields[1:] {
+		if field == "" {
+			return nil, SideBlack, fmt.Errorf("(ParseFEN) - empty piece list in %q", fen)
+		}
+		pieceType, playerID := "b", blackID
+		switch field[0] {
+		case 'B':
+		case 'W':
+			pieceType, playerID = "w", whiteID
+		default:
+			return nil, SideBlack, fmt.Errorf("(ParseFEN) - invalid piece list %q", field)
+		}
+		if len(field) == 1 {
+			continue // No pieces of this colour.
+		}
+		for _, square := range strings.Split(field[1:], ",") {
+			square, taken := strings.CutPrefix(square, "X")
+			square, king := strings.CutPrefix(square, "K")
+			squares, err := parseFENSquares(square, len(position.geometry().names))
+			if err != nil {
+				return nil, SideBlack, err
+			}
+			for _, sq := range squares {
+				pos := position.SquareName(sq)
+				if board.Grid[pos] != nil {
+					return nil, SideBlack, fmt.Errorf("(ParseFEN) - square %d is listed twice", sq+1)
+				}
+				board.Grid[pos] = &Piece{Type: pieceType, PieceID: uuid.New().String(), PlayerID: playerID, IsKinged: king}
+				if taken {
+					board.Captured = append(board.Captured, pos)
+				}
+			}
+		}
+	}
+	return board, sideToMove, nil
+}
+
+// parseFENSquares parses a square number or a range, e.g. "12" or "1-12", into bitboard indexes.
+func parseFENSquares(value string, numSquares int) ([]int, error) {
+	first, last, isRange := strings.Cut(value, "-")
+	from, err := strconv.Atoi(first)
+	if err != nil || from < 1 || from > numSquares {
+		return nil, fmt.Errorf("(ParseFEN) - invalid square %q", value)
+	}
+	to := from
+	if isRange {
+		to, err = strconv.Atoi(last)
+		if err != nil || to < from || to > numSquares {
+			return nil, fmt.Errorf("(ParseFEN) - invalid square range %q", value)
+		}
+	}
+	var squares []int
+	for sq := from; sq <= to; sq++ {
+		squares = append(squares, sq-1)
+	}
+	return squares, nil
+}
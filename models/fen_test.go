@@ -0,0 +1,163 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFENRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		fen     string
+		variant string
+		want    string // FEN written back, the same as fen when empty.
+	}{
+		{name: "end game test position", fen: EndGameTestFEN, variant: "classic"},
+		{name: "multiple capture test position", fen: MultipleCaptureTestFEN, variant: "classic"},
+		{name: "kings and white to move", fen: "W:WK4,29,30:B1,K12", variant: "english"},
+		{name: "ranges", fen: "B:W21-32:B1-12", variant: "classic", want: "B:W21,22,23,24,25,26,27,28,29,30,31,32:B1,2,3,4,5,6,7,8,9,10,11,12"},
+		{name: "lists in any order", fen: "B:B1,2:W31", variant: "classic", want: "B:W31:B1,2"},
+		{name: "empty side", fen: "W:W5:B", variant: "classic"},
+		{name: "10x10 squares", fen: "B:W46,K50:B1,K5", variant: "international"},
+		{name: "pieces taken mid capture", fen: "B:WX15,XK23,28:B1", variant: "brazilian"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, side, err := ParseFEN(tt.fen, testBlackID, testWhiteID, tt.variant)
+			if err != nil {
+				t.Fatalf("ParseFEN(%q): %v", tt.fen, err)
+			}
+			want := tt.want
+			if want == "" {
+				want = tt.fen
+			}
+			if got := board.FEN(side); got != want {
+				t.Errorf("FEN() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFENMidCapture(t *testing.T) {
+	game := testGame(t, MultipleCaptureTestFEN, "brazilian")
+	pieceID := game.Board.Grid["C5"].PieceID
+	if _, err := game.PlayHop(Move{PlayerID: testBlackID, PieceID: pieceID, From: "C5", To: "E7", IsCapture: true}); err != nil {
+		t.Fatalf("PlayHop: %v", err)
+	}
+	fen := game.Board.FEN(SideBlack)
+	board, _, err := ParseFEN(fen, testBlackID, testWhiteID, "brazilian")
+	if err != nil {
+		t.Fatalf("ParseFEN(%q): %v", fen, err)
+	}
+	if !slices.Equal(board.Captured, game.Board.Captured) {
+		t.Errorf("ParseFEN(%q) Captured = %v, want %v", fen, board.Captured, game.Board.Captured)
+	}
+	if got, want := moveNotation(board.continuationMoves("E7")), moveNotation(game.Board.continuationMoves("E7")); !slices.Equal(got, want) {
+		t.Errorf("continuation moves after ParseFEN(%q) = %v, want %v", fen, got, want)
+	}
+}
+
+func TestParseFENBoard(t *testing.T) {
+	board, side, err := ParseFEN(MultipleCaptureTestFEN, testBlackID, testWhiteID, "classic")
+	if err != nil {
+		t.Fatalf("ParseFEN: %v", err)
+	}
+	if side != SideBlack {
+		t.Errorf("side to move = %s, want B", side)
+	}
+	black, white := board.playerSquares(testBlackID), board.playerSquares(testWhiteID)
+	for _, pos := range white {
+		if board.Grid[pos].Type != "w" {
+			t.Errorf("piece on %s has type %s, want w", pos, board.Grid[pos].Type)
+		}
+	}
+	if want := []string{"A1", "A3", "A7", "B2", "B4", "C5"}; !slices.Equal(black, want) {
+		t.Errorf("black pieces on %v, want %v", black, want)
+	}
+	if want := []string{"D6", "F6"}; !slices.Equal(white, want) {
+		t.Errorf("white pieces on %v, want %v", white, want)
+	}
+	if len(board.Grid) != 64 {
+		t.Errorf("board has %d squares, want 64", len(board.Grid))
+	}
+}
+
+func TestParseFENErrors(t *testing.T) {
+	for _, fen := range []string{
+		"",
+		"B:W5",
+		"X:W5:B1",
+		"B:W5:X1",
+		"B:W5:B33",
+		"B:W5:B0",
+		"B:W5:B12-3",
+		"B:W5:B5",
+		"B:W5,a:B1",
+		"B:W5:KX1",
+	} {
+		if _, _, err := ParseFEN(fen, testBlackID, testWhiteID, "classic"); err == nil {
+			t.Errorf("ParseFEN(%q) returned no error", fen)
+		}
+	}
+}
+
+func TestMultipleCaptureTestFENForcesTheMultiJump(t *testing.T) {
+	game := testGame(t, MultipleCaptureTestFEN, "classic")
+	moves := game.LegalMoves()
+	if got := moveNotation(moves); !slices.Equal(got, []string{"C5xE7xG5"}) {
+		t.Fatalf("LegalMoves() = %v, want the double capture C5xE7xG5", got)
+	}
+
+	// The first hop alone leaves the turn to the same piece, which must finish the sequence.
+	pieceID := game.Board.Grid["C5"].PieceID
+	if err := game.ValidateMove(Move{PlayerID: testBlackID, PieceID: pieceID, From: "C5", To: "D4"}); err == nil {
+		t.Error("ValidateMove accepted a simple move while a capture is available")
+	}
+	result, err := game.PlayHop(moves[0].Hops(testBlackID)[0])
+	if err != nil {
+		t.Fatalf("PlayHop: %v", err)
+	}
+	if !result.TurnContinues || game.Continuation == nil || game.Continuation.Square != "E7" {
+		t.Fatalf("TurnContinues = %v, Continuation = %v, want the piece on E7 to keep capturing", result.TurnContinues, game.Continuation)
+	}
+	if got := moveNotation(game.LegalMoves()); !slices.Equal(got, []string{"E7xG5"}) {
+		t.Errorf("continuation LegalMoves() = %v, want [E7xG5]", got)
+	}
+	if got := game.FEN(); got != "B:W23:B1,2,4,5,6,20" {
+		t.Errorf("FEN() after the first hop = %s, want B:W23:B1,2,4,5,6,20", got)
+	}
+}
+
+func TestEndGameTestFENEndsTheGame(t *testing.T) {
+	game := testGame(t, EndGameTestFEN, "classic")
+	moves := game.LegalMoves()
+	if got := moveNotation(moves); !slices.Equal(got, []string{"A1xC3"}) {
+		t.Fatalf("LegalMoves() = %v, want [A1xC3]", got)
+	}
+	if _, err := game.PlayHop(moves[0].Hops(testBlackID)[0]); err != nil {
+		t.Fatalf("PlayHop: %v", err)
+	}
+	if !game.CheckGameOver() {
+		t.Error("CheckGameOver() = false after the last white piece was captured")
+	}
+}
+
+func TestNewGameStartFENOnlyInPractice(t *testing.T) {
+	setGameworkerConfig(t, `{"start_fen": "`+EndGameTestFEN+`"}`)
+	for _, practice := range []bool{true, false} {
+		room := &Room{
+			ID:              "room",
+			Player1:         &Player{ID: testBlackID},
+			Player2:         &Player{ID: testWhiteID},
+			CurrentPlayerID: testBlackID,
+			IsPractice:      practice,
+		}
+		want := EndGameTestFEN
+		if !practice {
+			want = ""
+		}
+		if game := room.NewGame(); game.StartFEN != want {
+			t.Errorf("NewGame() of a room with IsPractice %v started from %q, want %q", practice, game.StartFEN, want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/Lavizord/checkers-server/config"
@@ -30,6 +31,7 @@ type GamePlayer struct {
 	Color     string `json:"color"`
 	SessionID string `json:"session_id"`
 	NumPieces int    `json:"num_pieces"`
+	IsBot     bool   `json:"is_bot,omitempty"`
 }
 
 type Game struct {
@@ -42,9 +44,30 @@ type Game struct {
 	StartTime          time.Time          `json:"start_time"`
 	EndTime            time.Time          `json:"end_time"`
 	Winner             string             `json:"winner"`
-	BetValue           float64            `json:"bet_value"` // Bet amount for the game
-	TimerSetting       string             `json:"timer_settings"`
+	BetValue           float64            `json:"bet_value"`      // Bet amount for the game
+	TimerSetting       string             `json:"timer_settings"` // Mode of the TimeControl, kept for the clients that read it.
+	TimeControl        TimeControl        `json:"time_control"`
 	OperatorIdentifier OperatorIdentifier `json:"operator_identifier"`
+	Variant            string             `json:"variant"`               // Rules the game is played with, see RuleSets.
+	IsPractice         bool               `json:"is_practice,omitempty"` // Practice games are free, they never reach the operator wallet.
+	StartFEN           string             `json:"start_fen,omitempty"`   // Position the game started from, empty for the initial position of the variant.
+
+	MovesWithoutProgress int            `json:"moves_without_progress"` // Moves since the last capture or man move.
+	PositionCounts       map[string]int `json:"position_counts"`        // Times each position was reached, see Board.PositionKey.
+	DrawRule             string         `json:"draw_rule,omitempty"`    // Rule or agreement that ended the game in a draw, empty when it was not a draw.
+	DrawOffer            *DrawOffer     `json:"draw_offer,omitempty"`   // Pending draw offer, cleared on turn change.
+	DrawOffersMade       map[string]int `json:"draw_offers_made"`       // Draw offers made by each player.
+
+	Continuation  *Continuation `json:"continuation,omitempty"` // Set while the current player must keep capturing.
+	TurnStartedAt time.Time     `json:"turn_started_at"`        // When the current player got the turn.
+
+	Revision int `json:"revision"` // Incremented by every save, see redisdb UpdateGame.
+}
+
+// Continuation is the piece that must keep capturing after a hop, the next move must start with it.
+type Continuation struct {
+	PieceID string `json:"piece_id"`
+	Square  string `json:"square"`
 }
 
 // Move represents a single move in the game
@@ -55,6 +78,16 @@ type Move struct {
 	To        string `json:"to"`         // e.g., "B2"
 	IsCapture bool   `json:"is_capture"` // Whether the move captured an opponent's piece
 	IsKinged  bool   `json:"is_kinged"`  // Whether the piece was kinged after the move
+	// Optional full capture sequence, e.g. ["C3", "E5", "G3"], validated and applied in one go.
+	// When set From and To are ignored, each hop is stored as its own move.
+	Path []string `json:"path,omitempty"`
+	FEN  string   `json:"fen,omitempty"` // Position after the move, set by the server.
+
+	// Timing of the move, set by the server with Game.TimeMove. Every hop of a capture sequence
+	// sent as a path gets the same times.
+	ReceivedAt     time.Time `json:"received_at"`               // When the server received the move.
+	ClockRemaining int       `json:"clock_remaining,omitempty"` // Seconds left on the mover clock.
+	ThinkTime      int64     `json:"think_time_ms,omitempty"`   // Milliseconds since the mover got the turn.
 }
 
 func MapPlayerToGamePlayer(player Player) GamePlayer {
@@ -64,6 +97,7 @@ func MapPlayerToGamePlayer(player Player) GamePlayer {
 		Token:     player.Token,
 		SessionID: player.SessionID,
 		Timer:     0,
+		IsBot:     player.IsBot,
 	}
 }
 
@@ -82,22 +116,42 @@ func mapPlayers(r *Room) []GamePlayer {
 
 func (r *Room) NewGame() *Game {
 	whiteID, _ := r.GetOpponentPlayerID(r.CurrentPlayerID)
+	variant := GetRuleSet(r.OperatorIdentifier.Variant).Name
+
+	// Games start from the initial position, practice games can start from a configured test position.
+	board, currentPlayerID, startFEN := NewBoard(r.CurrentPlayerID, whiteID, variant), r.CurrentPlayerID, ""
+	if fen := config.Cfg.Services["gameworker"].StartFEN; fen != "" && r.IsPractice {
+		fenBoard, sideToMove, err := ParseFEN(fen, r.CurrentPlayerID, whiteID, variant)
+		if err != nil {
+			log.Printf("(NewGame) - Invalid start_fen, using the initial position: %v\n", err)
+		} else {
+			board, startFEN = fenBoard, fenBoard.FEN(sideToMove)
+			if sideToMove == SideWhite {
+				currentPlayerID = whiteID
+			}
+		}
+	}
 
+	now := time.Now()
 	game := Game{
-		ID:    r.ID,
-		Board: *NewBoard(r.CurrentPlayerID, whiteID, "std-game"),
-		//Board:           *NewBoard(r.CurrentPlayerID, whiteID, "two-pieces-endgame"),
-		//Board:           *NewBoard(r.CurrentPlayerID, whiteID, "multiple-capture"),
+		ID:                 r.ID,
+		Board:              *board,
+		Variant:            variant,
 		Players:            mapPlayers(r),
-		CurrentPlayerID:    r.CurrentPlayerID,
+		CurrentPlayerID:    currentPlayerID,
 		Turn:               0,
 		Moves:              []Move{},
-		StartTime:          time.Now(),
+		PositionCounts:     map[string]int{},
+		StartTime:          now,
+		TurnStartedAt:      now,
 		Winner:             "",
 		BetValue:           r.BetValue,
-		TimerSetting:       config.Cfg.Services["gameworker"].TimerSetting,
+		IsPractice:         r.IsPractice,
+		StartFEN:           startFEN,
 		OperatorIdentifier: r.OperatorIdentifier,
 	}
+	game.TimeControl = SelectTimeControl(r.OperatorIdentifier.TimeControl, r.BetValue)
+	game.TimerSetting = game.TimeControl.Mode
 
 	if game.Players[0].ID == whiteID {
 		game.Players[0].Color = "w"
@@ -111,30 +165,49 @@ func (r *Room) NewGame() *Game {
 	return &game
 }
 
+// SetUpPlayerTimers starts both clocks with the time control base.
 func (g *Game) SetUpPlayerTimers() {
+	for i := range g.Players {
+		g.Players[i].Timer = g.TimeControl.Base
+	}
+}
 
-	switch g.TimerSetting {
-	case "reset":
-		g.Players[0].Timer = config.Cfg.Services["gameworker"].Timer
-		g.Players[1].Timer = g.Players[0].Timer
-
-	case "cumulative":
-		calculatedTimer := config.Cfg.Services["gameworker"].Timer * config.Cfg.Services["gameworker"].PiecesInMatch
-		g.Players[0].Timer = calculatedTimer + 1
-		g.Players[1].Timer = g.Players[0].Timer
+func (g *Game) CalcGameMaxTimer() (int, error) {
+	return g.TimeControl.Base, nil
+}
 
+// ClockRemaining returns the seconds left to the current player at the given time, 0 or less once the
+// time has run out. GamePlayer.Timer holds each clock as it was when the player got the turn.
+func (g *Game) ClockRemaining(at time.Time) int {
+	player, err := g.GetGamePlayer(g.CurrentPlayerID)
+	if err != nil {
+		return 0
+	}
+	if g.TurnStartedAt.IsZero() {
+		return player.Timer
 	}
+	return g.TimeControl.Remaining(player.Timer, at.Sub(g.TurnStartedAt))
 }
 
-func (g *Game) CalcGameMaxTimer() (int, error) {
-	switch g.TimerSetting {
-	case "reset":
-		return config.Cfg.Services["gameworker"].Timer, nil
+// ClockDeadline returns when the current player runs out of time.
+func (g *Game) ClockDeadline() time.Time {
+	turnStart := g.TurnStartedAt
+	if turnStart.IsZero() {
+		turnStart = time.Now()
+	}
+	return turnStart.Add(time.Duration(g.ClockRemaining(turnStart)) * time.Second)
+}
 
-	case "cumulative":
-		return config.Cfg.Services["gameworker"].Timer * config.Cfg.Services["gameworker"].PiecesInMatch, nil
+// chargeClock takes the time of the turn from the current player clock, before the turn changes.
+func (g *Game) chargeClock(now time.Time) {
+	if g.TurnStartedAt.IsZero() {
+		return
+	}
+	for i := range g.Players {
+		if g.Players[i].ID == g.CurrentPlayerID {
+			g.Players[i].Timer = g.TimeControl.AfterTurn(g.Players[i].Timer, now.Sub(g.TurnStartedAt))
+		}
 	}
-	return 0, nil
 }
 
 func (g *Game) CountPlayerPieces(playerID string) int {
@@ -208,14 +281,115 @@ func (g *Game) UpdatePlayerTimer(playerID string, timer int) error {
 	return fmt.Errorf("player not found for player ID: %s", playerID)
 }
 
-// Updates player id and turn count.
+// TimeMove sets the timing of a move received at the given time, from the start of the current turn
+// and the mover clock. Games stored before turns were timed only get the receive time.
+func (g *Game) TimeMove(move Move, receivedAt time.Time) Move {
+	move.ReceivedAt = receivedAt
+	if g.TurnStartedAt.IsZero() {
+		return move
+	}
+	elapsed := max(receivedAt.Sub(g.TurnStartedAt), 0)
+	move.ThinkTime = elapsed.Milliseconds()
+	if move.PlayerID == g.CurrentPlayerID {
+		move.ClockRemaining = max(g.ClockRemaining(g.TurnStartedAt.Add(elapsed)), 0)
+	}
+	return move
+}
+
+// Updates player id and turn count, the time of the turn is taken from the clock of the player.
 func (g *Game) NextPlayer() {
+	now := time.Now()
+	g.chargeClock(now)
+	g.passTurn()
+	g.TurnStartedAt = now
+}
+
+// passTurn gives the turn to the opponent without touching the clocks, for the games replayed or
+// imported rather than played.
+func (g *Game) passTurn() {
 	nextPlayerId, err := g.GetOpponentPlayerID(g.CurrentPlayerID)
 	if err != nil {
 		log.Printf("Error NextPlayer getting opponent ID: %v\n", err)
 	}
 	g.CurrentPlayerID = nextPlayerId
 	g.Turn += 1
+	g.DrawOffer = nil // Draw offers expire when the turn changes.
+	g.Continuation = nil
+	// A capture sequence left unfinished, e.g. on a timeout, still takes the pieces it captured.
+	if len(g.Board.Captured) > 0 {
+		g.Board.RemoveCaptured()
+		g.UpdatePlayerPieces()
+	}
+}
+
+// SetContinuation records if the player must keep capturing with the piece that made the hop.
+func (g *Game) SetContinuation(move Move, turnContinues bool) {
+	if !turnContinues {
+		g.Continuation = nil
+		return
+	}
+	g.Continuation = &Continuation{PieceID: move.PieceID, Square: move.To}
+}
+
+// LegalMoves returns the moves the current player can make, only the continuation captures while one is pending.
+func (g *Game) LegalMoves() []LegalMove {
+	if g.Continuation != nil {
+		return g.Board.continuationMoves(g.Continuation.Square)
+	}
+	return g.Board.LegalMoves(g.CurrentPlayerID)
+}
+
+// ValidateMove checks a single hop, while a continuation is pending it must be made by the capturing piece.
+// Errors are always a *MoveError.
+func (g *Game) ValidateMove(move Move) error {
+	if err := g.checkTurn(move); err != nil {
+		return err
+	}
+	if err := g.checkContinuation(move.PieceID, move.From); err != nil {
+		return err
+	}
+	return g.Board.validateHop(move, g.moveSquares(move.PlayerID))
+}
+
+// ValidatePath checks a full move, while a continuation is pending it must finish the capture sequence.
+func (g *Game) ValidatePath(move Move) (LegalMove, error) {
+	if err := g.checkTurn(move); err != nil {
+		return LegalMove{}, err
+	}
+	if len(move.Path) > 0 {
+		if err := g.checkContinuation(move.PieceID, move.Path[0]); err != nil {
+			return LegalMove{}, err
+		}
+	}
+	return g.Board.validatePath(move, g.moveSquares(move.PlayerID))
+}
+
+func (g *Game) checkTurn(move Move) error {
+	if g.IsOver() {
+		return NewMoveError(ErrGameOver, "the game is over")
+	}
+	if move.PlayerID != g.CurrentPlayerID {
+		return NewMoveError(ErrNotYourTurn, "it is not the player turn")
+	}
+	return nil
+}
+
+func (g *Game) checkContinuation(pieceID, from string) error {
+	if g.Continuation == nil {
+		return nil
+	}
+	if pieceID != g.Continuation.PieceID || from != g.Continuation.Square {
+		return NewMoveError(ErrMustContinue, "must continue capturing with piece %s from %s", g.Continuation.PieceID, g.Continuation.Square)
+	}
+	return nil
+}
+
+// moveSquares returns the squares the player can move from.
+func (g *Game) moveSquares(playerID string) []string {
+	if g.Continuation != nil {
+		return []string{g.Continuation.Square}
+	}
+	return g.Board.playerSquares(playerID)
 }
 
 func (g *Game) RemovePiece(pos string) {
@@ -224,58 +398,108 @@ func (g *Game) RemovePiece(pos string) {
 	}
 }
 
-func (g *Game) MovePiece(move Move) bool {
+// MoveResult is the outcome of a hop decided by the server, it is sent to both players.
+type MoveResult struct {
+	Move            Move   `json:"move"`                        // The hop, with IsKinged and FEN set.
+	CapturedSquare  string `json:"captured_square,omitempty"`   // Square of the piece removed by the hop.
+	CapturedPieceID string `json:"captured_piece_id,omitempty"` // ID of the piece removed by the hop.
+	Kinged          bool   `json:"kinged"`                      // The piece was promoted on the destination square.
+	TurnContinues   bool   `json:"turn_continues"`              // The same piece must keep capturing.
+}
 
+// MovePiece moves the piece, removes the captured one and promotes the piece when due. With the
+// RemoveCapturedAtEnd rule the captured pieces are removed by the last hop of the sequence.
+func (g *Game) MovePiece(move Move) (MoveResult, error) {
 	// Validate move
 	piece, exists := g.Board.Grid[move.From]
 	if !exists || piece == nil || piece.PieceID != move.PieceID || piece.PlayerID != move.PlayerID {
-		// Invalid move, update and break
-		return false
+		return MoveResult{Move: move}, NewMoveError(ErrMoveFailed, "failed to move piece %s from %s to %s", move.PieceID, move.From, move.To)
+	}
+	result := MoveResult{}
+
+	// Handle capture, the captured piece is the first one between the from and to positions. This covers
+	// men of every variant and flying kings that land further behind the captured piece.
+	if move.IsCapture {
+		capturePos, found := g.Board.capturedSquare(move.From, move.To)
+		if !found {
+			return MoveResult{Move: move}, NewMoveError(ErrMoveFailed, "no piece to capture between %s and %s", move.From, move.To)
+		}
+		result.CapturedSquare = capturePos
+		result.CapturedPieceID = g.Board.Grid[capturePos].PieceID
+		if g.Board.Rules().RemoveCapturedAtEnd {
+			g.Board.Captured = append(g.Board.Captured, capturePos) // Removed when the sequence ends.
+		} else {
+			g.Board.Grid[capturePos] = nil // Remove the captured piece
+		}
 	}
 
 	// Move piece to new position
 	g.Board.Grid[move.To] = piece
 	g.Board.Grid[move.From] = nil
 
-	//if move.IsCapture && !piece.IsKinged {
-	//	midRow := (move.From[0] + move.To[0]) / 2
-	//	midCol := (move.From[1] + move.To[1]) / 2
-	//	capturePos := fmt.Sprintf("%c%c", midRow, midCol)
-	//	g.Board.Grid[capturePos] = nil // Remove captured piece
-	//}
-	// Handle capture
-	if move.IsCapture {
-		var capturePos string
-		if piece.IsKinged {
-			// For kinged pieces, the captured piece is the last square before the landing position
-			fromRow, fromCol := move.From[0], move.From[1]
-			toRow, toCol := move.To[0], move.To[1]
-
-			// Calculate the direction of movement
-			rowStep := 1
-			if toRow < fromRow {
-				rowStep = -1
-			}
-			colStep := 1
-			if toCol < fromCol {
-				colStep = -1
-			}
+	result.Kinged, result.TurnContinues = g.Board.FinishHop(move.To, move.IsCapture)
+	if !result.TurnContinues {
+		g.Board.RemoveCaptured()
+	}
+	move.IsKinged = result.Kinged
+	result.Move = move
+	return result, nil
+}
 
-			// Calculate the position of the captured piece
-			captureRow := toRow - byte(rowStep)
-			captureCol := toCol - byte(colStep)
-			capturePos = fmt.Sprintf("%c%c", captureRow, captureCol)
-		} else {
-			// For regular pieces, the captured piece is in the middle of the from and to positions
-			midRow := (move.From[0] + move.To[0]) / 2
-			midCol := (move.From[1] + move.To[1]) / 2
-			capturePos = fmt.Sprintf("%c%c", midRow, midCol)
+// PlayHop applies a validated hop with MovePiece, updates the draw counters and the continuation,
+// and adds the hop to the game moves with the FEN of the new position.
+func (g *Game) PlayHop(move Move) (MoveResult, error) {
+	piece := g.Board.GetPieceByID(move.PieceID)
+	if piece == nil {
+		return MoveResult{Move: move}, NewMoveError(ErrMoveFailed, "piece %s not found", move.PieceID)
+	}
+	movedMan := !piece.IsKinged
+	result, err := g.MovePiece(move)
+	if err != nil {
+		return result, err
+	}
+	g.UpdatePlayerPieces()
+	g.RecordMoveProgress(result.Move, movedMan)
+	g.SetContinuation(result.Move, result.TurnContinues)
+	sideToMove := PieceSide(*piece)
+	if !result.TurnContinues {
+		sideToMove = sideToMove.Opponent()
+	}
+	result.Move.FEN = g.Board.FEN(sideToMove)
+	g.Moves = append(g.Moves, result.Move)
+	return result, nil
+}
+
+// PlayHops plays the hops of a move one after the other with PlayHop. When a hop fails its result is
+// returned with the error and the hops before it stay played, the game must then be dropped rather
+// than saved so the move is applied whole or not at all.
+func (g *Game) PlayHops(hops []Move) ([]MoveResult, MoveResult, error) {
+	results := make([]MoveResult, 0, len(hops))
+	for _, hop := range hops {
+		result, err := g.PlayHop(hop)
+		if err != nil {
+			return results, result, err
 		}
+		results = append(results, result)
+	}
+	return results, MoveResult{}, nil
+}
 
-		// Remove the captured piece
-		g.Board.Grid[capturePos] = nil
+// StartSide returns the side that moved first, black unless the game started from a FEN with white to move.
+func (g *Game) StartSide() Side {
+	if strings.HasPrefix(g.StartFEN, SideWhite.String()) {
+		return SideWhite
 	}
-	return true
+	return SideBlack
+}
+
+// FEN returns the current position with the current player to move.
+func (g *Game) FEN() string {
+	side := SideBlack
+	if player, err := g.GetGamePlayer(g.CurrentPlayerID); err == nil && player.Color == "w" {
+		side = SideWhite
+	}
+	return g.Board.FEN(side)
 }
 
 func (g *Game) CheckGameOver() bool {
@@ -288,7 +512,23 @@ func (g *Game) CheckGameOver() bool {
 	return false // Game continues if both players have pieces
 }
 
+// IsCurrentPlayerBlocked reports if the player to move has no legal move left.
+func (g *Game) IsCurrentPlayerBlocked() bool {
+	return !g.Board.HasLegalMoves(g.CurrentPlayerID)
+}
+
+// IsOver reports if the game was finished, it can still be in redis while it is being closed.
+func (g *Game) IsOver() bool {
+	return !g.EndTime.IsZero()
+}
+
 func (g *Game) FinishGame(winnerID string) {
 	g.Winner = winnerID
 	g.EndTime = time.Now()
 }
+
+// FinishGameAsDraw ends the game without a winner, drawRule marks it as a draw and must not be empty.
+func (g *Game) FinishGameAsDraw(drawRule string) {
+	g.DrawRule = drawRule
+	g.FinishGame("")
+}
@@ -0,0 +1,300 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPlayHopRemovesCapturedPieces(t *testing.T) {
+	tests := []struct {
+		variant      string
+		wantMidHop   []string // Pieces on the captured squares after the first hop.
+		wantCaptured []string // Board.Captured after the first hop.
+	}{
+		{variant: "classic", wantMidHop: []string{"F6"}},
+		{variant: "brazilian", wantMidHop: []string{"D6", "F6"}, wantCaptured: []string{"D6"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.variant, func(t *testing.T) {
+			game := testGame(t, MultipleCaptureTestFEN, tt.variant)
+			pieceID := game.Board.Grid["C5"].PieceID
+			hops := LegalMove{PieceID: pieceID, Path: []string{"C5", "E7", "G5"}, Captures: []string{"D6", "F6"}}.Hops(testBlackID)
+
+			result, err := game.PlayHop(hops[0])
+			if err != nil {
+				t.Fatalf("PlayHop(%s-%s): %v", hops[0].From, hops[0].To, err)
+			}
+			if !result.TurnContinues || result.CapturedSquare != "D6" {
+				t.Errorf("first hop TurnContinues = %v, CapturedSquare = %s, want true and D6", result.TurnContinues, result.CapturedSquare)
+			}
+			if got := occupiedSquares(game.Board, "D6", "F6"); !slices.Equal(got, tt.wantMidHop) {
+				t.Errorf("pieces after the first hop on %v, want %v", got, tt.wantMidHop)
+			}
+			if !slices.Equal(game.Board.Captured, tt.wantCaptured) {
+				t.Errorf("Captured = %v, want %v", game.Board.Captured, tt.wantCaptured)
+			}
+
+			result, err = game.PlayHop(hops[1])
+			if err != nil {
+				t.Fatalf("PlayHop(%s-%s): %v", hops[1].From, hops[1].To, err)
+			}
+			if result.TurnContinues || result.CapturedSquare != "F6" {
+				t.Errorf("last hop TurnContinues = %v, CapturedSquare = %s, want false and F6", result.TurnContinues, result.CapturedSquare)
+			}
+			if got := occupiedSquares(game.Board, "D6", "F6"); len(got) != 0 || len(game.Board.Captured) != 0 {
+				t.Errorf("pieces left on %v and Captured = %v after the sequence", got, game.Board.Captured)
+			}
+			if white, _ := game.GetGamePlayer(testWhiteID); white.NumPieces != 0 {
+				t.Errorf("white NumPieces = %d, want 0", white.NumPieces)
+			}
+		})
+	}
+}
+
+func TestNextPlayerRemovesCapturedPieces(t *testing.T) {
+	game := testGame(t, MultipleCaptureTestFEN, "brazilian")
+	pieceID := game.Board.Grid["C5"].PieceID
+	if _, err := game.PlayHop(Move{PlayerID: testBlackID, PieceID: pieceID, From: "C5", To: "E7", IsCapture: true}); err != nil {
+		t.Fatalf("PlayHop: %v", err)
+	}
+	game.NextPlayer() // The player ran out of time in the middle of the sequence.
+	if game.Board.Grid["D6"] != nil || len(game.Board.Captured) != 0 {
+		t.Errorf("captured piece still on D6 after the turn changed")
+	}
+	if white, _ := game.GetGamePlayer(testWhiteID); white.NumPieces != 1 {
+		t.Errorf("white NumPieces = %d, want 1", white.NumPieces)
+	}
+}
+
+// The turns of replayed and imported games change without running the clocks.
+func TestPassTurnLeavesTheClocks(t *testing.T) {
+	game := testGame(t, "", "classic")
+	game.TimeControl = TimeControl{Name: TimerCumulative, Mode: TimerCumulative, Base: 180}
+	game.SetUpPlayerTimers()
+	game.TurnStartedAt = time.Now().Add(-time.Minute)
+	turnStartedAt := game.TurnStartedAt
+
+	game.passTurn()
+	if game.CurrentPlayerID != testWhiteID || game.Turn != 1 {
+		t.Errorf("CurrentPlayerID = %s, Turn = %d, want white on turn 1", game.CurrentPlayerID, game.Turn)
+	}
+	if black, _ := game.GetGamePlayer(testBlackID); black.Timer != 180 || !game.TurnStartedAt.Equal(turnStartedAt) {
+		t.Errorf("black Timer = %d, TurnStartedAt = %v, want the clocks untouched", black.Timer, game.TurnStartedAt)
+	}
+}
+
+func TestTimeMove(t *testing.T) {
+	turnStartedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		playerID      string
+		turnStartedAt time.Time
+		receivedAfter time.Duration
+		wantThinkTime int64
+		wantClock     int
+	}{
+		{name: "move of the current player", playerID: testBlackID, turnStartedAt: turnStartedAt, receivedAfter: 3200 * time.Millisecond, wantThinkTime: 3200, wantClock: 297},
+		{name: "move after the clock ran out", playerID: testBlackID, turnStartedAt: turnStartedAt, receivedAfter: 301 * time.Second, wantThinkTime: 301000, wantClock: 0},
+		{name: "move received before the turn started", playerID: testBlackID, turnStartedAt: turnStartedAt, receivedAfter: -time.Second, wantThinkTime: 0, wantClock: 300},
+		{name: "move of the other player", playerID: testWhiteID, turnStartedAt: turnStartedAt, receivedAfter: 2 * time.Second, wantThinkTime: 2000, wantClock: 0},
+		{name: "game stored before turns were timed", playerID: testBlackID, receivedAfter: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := testGame(t, "", "classic")
+			game.TimeControl = TimeControl{Name: TimerCumulative, Mode: TimerCumulative, Base: 300}
+			game.SetUpPlayerTimers()
+			game.TurnStartedAt = tt.turnStartedAt
+			receivedAt := turnStartedAt.Add(tt.receivedAfter)
+
+			move := game.TimeMove(Move{PlayerID: tt.playerID, From: "C3", To: "D4"}, receivedAt)
+			if !move.ReceivedAt.Equal(receivedAt) {
+				t.Errorf("ReceivedAt = %v, want %v", move.ReceivedAt, receivedAt)
+			}
+			if move.ThinkTime != tt.wantThinkTime || move.ClockRemaining != tt.wantClock {
+				t.Errorf("ThinkTime = %dms, ClockRemaining = %ds, want %dms and %ds", move.ThinkTime, move.ClockRemaining, tt.wantThinkTime, tt.wantClock)
+			}
+		})
+	}
+}
+
+// occupiedSquares returns the squares of the list that hold a piece.
+func occupiedSquares(board Board, squares ...string) []string {
+	var occupied []string
+	for _, pos := range squares {
+		if board.Grid[pos] != nil {
+			occupied = append(occupied, pos)
+		}
+	}
+	return occupied
+}
+
+// pathMove is the move of the piece on the first square of the path by the current player.
+func pathMove(game *Game, path ...string) Move {
+	move := Move{PlayerID: game.CurrentPlayerID, From: path[0], To: path[len(path)-1], Path: path}
+	if piece := game.Board.Grid[path[0]]; piece != nil {
+		move.PieceID = piece.PieceID
+	}
+	return move
+}
+
+func TestValidatePath(t *testing.T) {
+	tests := []struct {
+		name         string
+		variant      string
+		fen          string
+		path         []string
+		wantCaptures []string
+		wantCode     MoveErrorCode // Empty when the path is legal.
+	}{
+		{name: "simple move", variant: "classic", path: []string{"C3", "D4"}},
+		{name: "whole multi-jump", variant: "classic", fen: MultipleCaptureTestFEN, path: []string{"C5", "E7", "G5"}, wantCaptures: []string{"D6", "F6"}},
+		{name: "multi-jump stopped half way", variant: "classic", fen: MultipleCaptureTestFEN, path: []string{"C5", "E7"}, wantCode: ErrIncompleteCapture},
+		{name: "simple move while a capture is available", variant: "classic", fen: "B:W14:B4,10", path: []string{"A7", "B8"}, wantCode: ErrMustCapture},
+		{name: "path of a single square", variant: "classic", path: []string{"C3"}, wantCode: ErrInvalidPath},
+		{name: "simple move with more squares", variant: "classic", path: []string{"C3", "D4", "E5"}, wantCode: ErrIllegalMove},
+		{name: "piece of the opponent", variant: "classic", path: []string{"F2", "E3"}, wantCode: ErrNotYourPiece},
+		{name: "shorter capture without the max capture rule", variant: "english", fen: "B:W13,15,23:B9,11", path: []string{"C1", "E3"}, wantCaptures: []string{"D2"}},
+		{name: "shorter capture with the max capture rule", variant: "brazilian", fen: "B:W13,15,23:B9,11", path: []string{"C1", "E3"}, wantCode: ErrMaxCapture},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := testGame(t, tt.fen, tt.variant)
+			lm, err := game.ValidatePath(pathMove(game, tt.path...))
+			if tt.wantCode != "" {
+				if got := moveErrorCode(err); got != tt.wantCode {
+					t.Errorf("ValidatePath(%v) = %v, want %s", tt.path, err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidatePath(%v): %v", tt.path, err)
+			}
+			if !slices.Equal(lm.Path, tt.path) || !slices.Equal(lm.Captures, tt.wantCaptures) {
+				t.Errorf("ValidatePath(%v) = %v capturing %v, want the path capturing %v", tt.path, lm.Path, lm.Captures, tt.wantCaptures)
+			}
+		})
+	}
+}
+
+func TestValidatePathNotYourTurn(t *testing.T) {
+	game := testGame(t, "", "classic")
+	move := pathMove(game, "C3", "D4")
+	move.PlayerID = testWhiteID
+	if _, err := game.ValidatePath(move); moveErrorCode(err) != ErrNotYourTurn {
+		t.Errorf("ValidatePath() out of turn = %v, want %s", err, ErrNotYourTurn)
+	}
+	game.FinishGame(testWhiteID)
+	if _, err := game.ValidatePath(pathMove(game, "C3", "D4")); moveErrorCode(err) != ErrGameOver {
+		t.Errorf("ValidatePath() once the game is over = %v, want %s", err, ErrGameOver)
+	}
+}
+
+func TestValidateMoveErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		variant  string
+		fen      string
+		from, to string
+		capture  bool
+		pieceOn  string // Square of the piece sent with the move, from when empty.
+		wantCode MoveErrorCode
+	}{
+		{name: "legal", variant: "classic", from: "C3", to: "D4"},
+		{name: "no piece", variant: "classic", from: "D4", to: "E5", wantCode: ErrNoPiece},
+		{name: "piece of the opponent", variant: "classic", from: "F2", to: "E3", wantCode: ErrNotYourPiece},
+		{name: "id of another piece", variant: "classic", from: "C3", to: "D4", pieceOn: "C5", wantCode: ErrPieceMismatch},
+		{name: "not diagonal", variant: "classic", from: "C3", to: "E3", wantCode: ErrNotDiagonal},
+		{name: "man moving backwards", variant: "classic", from: "C3", to: "B4", wantCode: ErrWrongDirection},
+		{name: "occupied square", variant: "classic", from: "B2", to: "C3", wantCode: ErrPathBlocked},
+		{name: "man moving two squares", variant: "classic", from: "C3", to: "E5", wantCode: ErrTooFar},
+		{name: "capture flag on a simple move", variant: "classic", from: "C3", to: "D4", capture: true, wantCode: ErrCaptureFlag},
+		{name: "capture without the capture flag", variant: "classic", fen: "B:W14:B4,10", from: "C3", to: "E5", wantCode: ErrCaptureFlag},
+		{name: "simple move while a capture is available", variant: "classic", fen: "B:W14:B4,10", from: "A7", to: "B8", wantCode: ErrMustCapture},
+		{name: "blocked player", variant: "classic", fen: "B:W13,18:B9", from: "C1", to: "D2", wantCode: ErrNoLegalMoves},
+		{name: "shorter capture", variant: "brazilian", fen: "B:W13,15,23:B9,11", from: "C1", to: "E3", capture: true, wantCode: ErrMaxCapture},
+		{name: "man capturing instead of the king", variant: "italian", fen: "B:W13,15:BK9,11", from: "C5", to: "E7", capture: true, wantCode: ErrKingMustCapture},
+		{name: "capture of fewer kings", variant: "italian", fen: "B:WK5,13:BK9", from: "C1", to: "E3", capture: true, wantCode: ErrMostKings},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := testGame(t, tt.fen, tt.variant)
+			move := pathMove(game, tt.from, tt.to)
+			move.Path, move.IsCapture = nil, tt.capture
+			if tt.pieceOn != "" {
+				move.PieceID = game.Board.Grid[tt.pieceOn].PieceID
+			}
+			err := game.ValidateMove(move)
+			if got := moveErrorCode(err); got != tt.wantCode {
+				t.Errorf("ValidateMove(%s-%s) = %v, want code %q", tt.from, tt.to, err, tt.wantCode)
+			}
+		})
+	}
+}
+
+// moveErrorCode returns the code of a MoveError, empty for nil.
+func moveErrorCode(err error) MoveErrorCode {
+	var moveErr *MoveError
+	if errors.As(err, &moveErr) {
+		return moveErr.Code
+	}
+	if err != nil {
+		return "not a MoveError"
+	}
+	return ""
+}
+
+func TestContinuation(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     []string
+		pieceOn  string // Square of the piece sent with the move, the first square of the path when empty.
+		wantCode MoveErrorCode
+	}{
+		{name: "same piece keeps capturing", path: []string{"E7", "G5"}},
+		{name: "another piece", path: []string{"A7", "B8"}, wantCode: ErrMustContinue},
+		{name: "id of another piece on the continuation square", path: []string{"E7", "G5"}, pieceOn: "A7", wantCode: ErrMustContinue},
+	}
+	for _, tt := range tests {
+		for _, sent := range []string{"hop", "path"} {
+			t.Run(tt.name+"/"+sent, func(t *testing.T) {
+				game := testGame(t, MultipleCaptureTestFEN, "classic")
+				first := pathMove(game, "C5", "E7")
+				first.IsCapture = true
+				if result, err := game.PlayHop(first); err != nil || !result.TurnContinues {
+					t.Fatalf("PlayHop(C5-E7) = %+v, %v, want the turn to continue", result, err)
+				}
+				move := pathMove(game, tt.path...)
+				if tt.pieceOn != "" {
+					move.PieceID = game.Board.Grid[tt.pieceOn].PieceID
+				}
+				var err error
+				if sent == "hop" {
+					move.Path, move.IsCapture = nil, tt.path[0] == "E7"
+					err = game.ValidateMove(move)
+				} else {
+					_, err = game.ValidatePath(move)
+				}
+				if got := moveErrorCode(err); got != tt.wantCode {
+					t.Errorf("move %v = %v, want code %q", tt.path, err, tt.wantCode)
+				}
+			})
+		}
+	}
+}
+
+func TestContinuationEndsWithTheTurn(t *testing.T) {
+	game := testGame(t, MultipleCaptureTestFEN, "classic")
+	first := pathMove(game, "C5", "E7")
+	first.IsCapture = true
+	game.PlayHop(first)
+	if game.Continuation == nil || game.Continuation.Square != "E7" || game.Continuation.PieceID != first.PieceID {
+		t.Fatalf("Continuation = %+v, want the piece on E7", game.Continuation)
+	}
+	game.NextPlayer() // The player ran out of time in the middle of the sequence.
+	if game.Continuation != nil {
+		t.Errorf("Continuation = %+v after the turn changed", game.Continuation)
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "encoding/json"
+
+// Types of the game commands.
+const (
+	GameCommandMove       = "move"       // Data is the Move.
+	GameCommandDraw       = "draw"       // Data is the DrawCommand.
+	GameCommandLeave      = "leave"      // Data is the Player leaving.
+	GameCommandDisconnect = "disconnect" // Data is the Player that disconnected.
+	GameCommandReconnect  = "reconnect"  // Data is the Player that reconnected.
+	GameCommandTimeout    = "timeout"    // No data, the clock deadline of the game passed.
+)
+
+// GameCommand is an event of a game. The gameworker runs the commands of a game one at a time, in the
+// order they were sent to the game mailbox.
+type GameCommand struct {
+	Type   string          `json:"type"`
+	GameID string          `json:"game_id"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// NewGameCommand builds a command with the data serialized.
+func NewGameCommand(commandType, gameID string, data any) (GameCommand, error) {
+	command := GameCommand{Type: commandType, GameID: gameID}
+	if data == nil {
+		return command, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return command, err
+	}
+	command.Data = raw
+	return command, nil
+}
@@ -69,6 +69,8 @@ type OperatorIdentifier struct {
 	OperatorGameName string  `json:"operator_game_name"`
 	GameName         string  `json:"game_name"`
 	WinFactor        float64 `json:"win_factor"`
+	Variant          string  `json:"variant"`                // Checkers rules used by the operator games, see RuleSets.
+	TimeControl      string  `json:"time_control,omitempty"` // Clock preset of the operator games, see TimeControls.
 }
 
 type PlayerCountPerBetValue struct {
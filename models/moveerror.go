@@ -0,0 +1,43 @@
+package models
+
+import "fmt"
+
+// MoveErrorCode tells the client why a move was rejected, it is sent in the invalid_move message.
+type MoveErrorCode string
+
+const (
+	ErrNotYourTurn       MoveErrorCode = "NOT_YOUR_TURN"
+	ErrNoPiece           MoveErrorCode = "NO_PIECE"           // There is no piece on the source square.
+	ErrNotYourPiece      MoveErrorCode = "NOT_YOUR_PIECE"     // The piece belongs to the opponent.
+	ErrPieceMismatch     MoveErrorCode = "PIECE_MISMATCH"     // The piece id is not the one on the source square.
+	ErrNoLegalMoves      MoveErrorCode = "NO_LEGAL_MOVES"     // The player is blocked.
+	ErrNotDiagonal       MoveErrorCode = "NOT_DIAGONAL"       // Pieces only move along the diagonals.
+	ErrWrongDirection    MoveErrorCode = "WRONG_DIRECTION"    // Men can't move backwards.
+	ErrTooFar            MoveErrorCode = "TOO_FAR"            // The piece can't move that many squares.
+	ErrPathBlocked       MoveErrorCode = "PATH_BLOCKED"       // The destination, or a square on the way, is occupied.
+	ErrMustCapture       MoveErrorCode = "MUST_CAPTURE"       // A capture is available and captures are mandatory.
+	ErrCaptureFlag       MoveErrorCode = "CAPTURE_FLAG"       // The is_capture flag does not match the move.
+	ErrMaxCapture        MoveErrorCode = "MAX_CAPTURE"        // A longer capture sequence must be taken.
+	ErrKingMustCapture   MoveErrorCode = "KING_MUST_CAPTURE"  // Between sequences of the same length the king must capture.
+	ErrMostKings         MoveErrorCode = "MOST_KINGS"         // The sequence that takes the most kings must be taken.
+	ErrMustContinue      MoveErrorCode = "MUST_CONTINUE"      // The piece that captured must keep capturing.
+	ErrIncompleteCapture MoveErrorCode = "INCOMPLETE_CAPTURE" // The path stops before the capture sequence ends.
+	ErrInvalidPath       MoveErrorCode = "INVALID_PATH"       // The path is too short.
+	ErrIllegalMove       MoveErrorCode = "ILLEGAL_MOVE"       // Any other move that is not in the legal moves.
+	ErrMoveFailed        MoveErrorCode = "MOVE_FAILED"        // The move was valid but could not be applied.
+	ErrGameOver          MoveErrorCode = "GAME_OVER"          // The game ended before the move arrived.
+)
+
+// MoveError is returned by the move validation, Code is the reason sent to the client.
+type MoveError struct {
+	Code    MoveErrorCode
+	Message string
+}
+
+func (e *MoveError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func NewMoveError(code MoveErrorCode, format string, args ...any) *MoveError {
+	return &MoveError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,515 @@
+package models
+
+import (
+	"slices"
+	"sort"
+)
+
+// LegalMove is a complete move for a single piece.
+//
+// A simple move has a two square Path. A capture lists every landing square of the
+// chain in Path and the squares of the pieces taken on the way in Captures, so
+// Path[i+1] is reached by taking Captures[i].
+type LegalMove struct {
+	PieceID  string   `json:"piece_id"`
+	Path     []string `json:"path"`     // e.g. ["C3", "E5", "G3"]
+	Captures []string `json:"captures"` // e.g. ["D4", "F4"]
+}
+
+func (lm LegalMove) IsCapture() bool {
+	return len(lm.Captures) > 0
+}
+
+func (lm LegalMove) From() string {
+	return lm.Path[0]
+}
+
+func (lm LegalMove) To() string {
+	return lm.Path[len(lm.Path)-1]
+}
+
+// FirstHop returns the single hop Move the clients send for the first step of this move.
+func (lm LegalMove) FirstHop(playerID string) Move {
+	return Move{
+		PlayerID:  playerID,
+		PieceID:   lm.PieceID,
+		From:      lm.Path[0],
+		To:        lm.Path[1],
+		IsCapture: lm.IsCapture(),
+	}
+}
+
+// Hops splits the move into the single hop Moves the gameworker applies and broadcasts.
+func (lm LegalMove) Hops(playerID string) []Move {
+	hops := make([]Move, 0, len(lm.Path)-1)
+	for i := 1; i < len(lm.Path); i++ {
+		hops = append(hops, Move{
+			PlayerID:  playerID,
+			PieceID:   lm.PieceID,
+			From:      lm.Path[i-1],
+			To:        lm.Path[i],
+			IsCapture: lm.IsCapture(),
+		})
+	}
+	return hops
+}
+
+var diagonals = []struct{ rowDelta, colDelta int }{
+	{1, 1},   // Diagonal right (down)
+	{1, -1},  // Diagonal left (down)
+	{-1, 1},  // Diagonal right (up)
+	{-1, -1}, // Diagonal left (up)
+}
+
+// LegalMoves returns every legal move for the player, with capture chains played out to the end.
+//
+// Captures are mandatory, so when any piece can capture only the capture sequences are returned,
+// and with the MandatoryMaxCapture rule only the ones that take the most pieces.
+// The result is sorted by origin square so callers get a stable order.
+func (b *Board) LegalMoves(playerID string) []LegalMove {
+	captures, simple := b.pseudoLegalMoves(b.playerSquares(playerID))
+	if len(captures) > 0 {
+		return b.Rules().filterCaptures(b, captures)
+	}
+	return simple
+}
+
+// continuationMoves returns the capture sequences the piece on pos can finish after a hop.
+func (b *Board) continuationMoves(pos string) []LegalMove {
+	captures := b.captureSequences(pos)
+	if len(captures) == 0 {
+		return nil
+	}
+	return b.Rules().filterCaptures(b, captures)
+}
+
+// pseudoLegalMoves returns the capture sequences of the pieces on the squares and, when there are none,
+// the simple moves, before the capture precedence rules are applied.
+func (b *Board) pseudoLegalMoves(squares []string) (captures, simple []LegalMove) {
+	for _, pos := range squares {
+		captures = append(captures, b.captureSequences(pos)...)
+		if len(captures) == 0 {
+			simple = append(simple, b.simpleMoves(pos)...)
+		}
+	}
+	return captures, simple
+}
+
+// filterCaptures keeps the capture sequences the rules allow the player to choose from.
+func (rs RuleSet) filterCaptures(b *Board, captures []LegalMove) []LegalMove {
+	if !rs.MandatoryMaxCapture {
+		return captures
+	}
+	captures = keepBest(captures, func(lm LegalMove) int { return len(lm.Captures) })
+	if !rs.KingCapturePriority {
+		return captures
+	}
+	captures = keepBest(captures, func(lm LegalMove) int {
+		if b.Grid[lm.From()].IsKinged {
+			return 1
+		}
+		return 0
+	})
+	return keepBest(captures, func(lm LegalMove) int { return b.kingsTaken(lm) })
+}
+
+// keepBest keeps the moves with the highest score.
+func keepBest(moves []LegalMove, score func(LegalMove) int) []LegalMove {
+	best := 0
+	for _, lm := range moves {
+		if s := score(lm); s > best {
+			best = s
+		}
+	}
+	var kept []LegalMove
+	for _, lm := range moves {
+		if score(lm) == best {
+			kept = append(kept, lm)
+		}
+	}
+	return kept
+}
+
+// kingsTaken counts the kings captured by the sequence.
+func (b *Board) kingsTaken(lm LegalMove) int {
+	kings := 0
+	for _, pos := range lm.Captures {
+		if piece := b.Grid[pos]; piece != nil && piece.IsKinged {
+			kings++
+		}
+	}
+	return kings
+}
+
+// LegalMovesFrom returns the legal moves of the player that start on the given square.
+func (b *Board) LegalMovesFrom(playerID, pos string) []LegalMove {
+	var moves []LegalMove
+	for _, lm := range b.LegalMoves(playerID) {
+		if lm.From() == pos {
+			moves = append(moves, lm)
+		}
+	}
+	return moves
+}
+
+func (b *Board) HasLegalMoves(playerID string) bool {
+	return len(b.LegalMoves(playerID)) > 0
+}
+
+// CanPieceCapture reports if the piece on the given square has a capture available.
+func (b *Board) CanPieceCapture(pos string) bool {
+	return len(b.captureSequences(pos)) > 0
+}
+
+// validateHop checks a single hop sent by a client against the legal moves of the pieces on the squares.
+//
+// The hop is valid when it is the first step of one of the legal moves, see Game.ValidateMove.
+// Errors are always a *MoveError.
+func (b *Board) validateHop(move Move, squares []string) error {
+	piece, err := b.checkSourcePiece(move, move.From)
+	if err != nil {
+		return err
+	}
+	captures, simple := b.pseudoLegalMoves(squares)
+	if len(captures) == 0 && len(simple) == 0 {
+		return NewMoveError(ErrNoLegalMoves, "player has no legal moves")
+	}
+	if len(captures) == 0 {
+		if lm, ok := findHop(simple, move); ok {
+			return checkCaptureFlag(lm, move)
+		}
+		return b.diagnoseHop(*piece, move.From, move.To)
+	}
+	legalMoves := b.Rules().filterCaptures(b, captures)
+	if lm, ok := findHop(legalMoves, move); ok {
+		return checkCaptureFlag(lm, move)
+	}
+	if lm, ok := findHop(captures, move); ok {
+		return b.captureRuleError(lm, legalMoves[0])
+	}
+	return NewMoveError(ErrMustCapture, "there are player pieces that can capture, must capture")
+}
+
+// validatePath checks a full move sent by a client, the path must be one of the legal moves of the pieces
+// on the squares, see Game.ValidatePath.
+//
+// Capture sequences must be played to the end, so a path that stops while the piece can still capture is rejected.
+func (b *Board) validatePath(move Move, squares []string) (LegalMove, error) {
+	if len(move.Path) < 2 {
+		return LegalMove{}, NewMoveError(ErrInvalidPath, "path needs at least two squares")
+	}
+	piece, err := b.checkSourcePiece(move, move.Path[0])
+	if err != nil {
+		return LegalMove{}, err
+	}
+	captures, simple := b.pseudoLegalMoves(squares)
+	if len(captures) == 0 && len(simple) == 0 {
+		return LegalMove{}, NewMoveError(ErrNoLegalMoves, "player has no legal moves")
+	}
+	if len(captures) == 0 {
+		if lm, ok := findPath(simple, move.Path); ok {
+			return lm, nil
+		}
+		if len(move.Path) > 2 {
+			return LegalMove{}, NewMoveError(ErrIllegalMove, "path %v is not a legal move, there are no captures", move.Path)
+		}
+		return LegalMove{}, b.diagnoseHop(*piece, move.Path[0], move.Path[1])
+	}
+	legalMoves := b.Rules().filterCaptures(b, captures)
+	if lm, ok := findPath(legalMoves, move.Path); ok {
+		return lm, nil
+	}
+	if lm, ok := findPath(captures, move.Path); ok {
+		return LegalMove{}, b.captureRuleError(lm, legalMoves[0])
+	}
+	if _, ok := findHop(captures, Move{From: move.Path[0], To: move.Path[1]}); ok {
+		return LegalMove{}, NewMoveError(ErrIncompleteCapture, "path %v does not complete a capture sequence", move.Path)
+	}
+	return LegalMove{}, NewMoveError(ErrMustCapture, "there are player pieces that can capture, must capture")
+}
+
+// checkSourcePiece checks the piece on the source square is the player piece sent in the move.
+func (b *Board) checkSourcePiece(move Move, from string) (*Piece, error) {
+	piece, exists := b.Grid[from]
+	if !exists || piece == nil {
+		return nil, NewMoveError(ErrNoPiece, "piece does not exist at %s", from)
+	}
+	if piece.PlayerID != move.PlayerID {
+		return nil, NewMoveError(ErrNotYourPiece, "piece at %s does not belong to the player", from)
+	}
+	if piece.PieceID != move.PieceID {
+		return nil, NewMoveError(ErrPieceMismatch, "piece id %s does not match the piece at %s", move.PieceID, from)
+	}
+	return piece, nil
+}
+
+// diagnoseHop explains why a hop that is not in the legal moves can't be played, when there are no captures.
+func (b *Board) diagnoseHop(piece Piece, from, to string) error {
+	fromRow, fromCol, err := parsePosition(from)
+	if err != nil {
+		return NewMoveError(ErrIllegalMove, "invalid square %s", from)
+	}
+	toRow, toCol, err := parsePosition(to)
+	if _, exists := b.Grid[to]; err != nil || !exists {
+		return NewMoveError(ErrIllegalMove, "invalid square %s", to)
+	}
+	rowDelta, colDelta := int(toRow-fromRow), toCol-fromCol
+	if rowDelta == 0 || (rowDelta != colDelta && rowDelta != -colDelta) {
+		return NewMoveError(ErrNotDiagonal, "move from %s to %s is not diagonal", from, to)
+	}
+	if !piece.IsKinged && (rowDelta > 0) != (GetPieceDirection(piece) > 0) {
+		return NewMoveError(ErrWrongDirection, "men can't move backwards")
+	}
+	dist := max(rowDelta, -rowDelta)
+	rowStep, colStep := rowDelta/dist, colDelta/dist
+	for i := 1; i <= dist; i++ {
+		square, _ := b.offsetSquare(from, rowStep*i, colStep*i)
+		if b.Grid[square] != nil {
+			return NewMoveError(ErrPathBlocked, "square %s is occupied", square)
+		}
+	}
+	if dist > 1 && !(piece.IsKinged && b.Rules().FlyingKings) {
+		return NewMoveError(ErrTooFar, "the piece can only move one square")
+	}
+	return NewMoveError(ErrIllegalMove, "move from %s to %s is not legal", from, to)
+}
+
+func findPath(moves []LegalMove, path []string) (LegalMove, bool) {
+	for _, lm := range moves {
+		if slices.Equal(lm.Path, path) {
+			return lm, true
+		}
+	}
+	return LegalMove{}, false
+}
+
+// findHop returns the first move whose first hop is the one sent by the client.
+func findHop(moves []LegalMove, move Move) (LegalMove, bool) {
+	for _, lm := range moves {
+		if lm.From() == move.From && lm.Path[1] == move.To {
+			return lm, true
+		}
+	}
+	return LegalMove{}, false
+}
+
+func checkCaptureFlag(lm LegalMove, move Move) error {
+	if lm.IsCapture() != move.IsCapture {
+		return NewMoveError(ErrCaptureFlag, "capture flag does not match the move")
+	}
+	return nil
+}
+
+// captureRuleError explains which capture precedence rule the played sequence breaks, compared to a legal one.
+func (b *Board) captureRuleError(played, legal LegalMove) error {
+	if len(played.Captures) < len(legal.Captures) {
+		return NewMoveError(ErrMaxCapture, "must take the longest capture sequence, %d pieces instead of %d", len(legal.Captures), len(played.Captures))
+	}
+	if b.Grid[legal.From()].IsKinged && !b.Grid[played.From()].IsKinged {
+		return NewMoveError(ErrKingMustCapture, "must capture with the king when the sequences take the same number of pieces")
+	}
+	return NewMoveError(ErrMostKings, "must take the capture sequence with the most kings, %d kings instead of %d", b.kingsTaken(legal), b.kingsTaken(played))
+}
+
+// Clone returns a deep copy of the board, pieces included.
+func (b *Board) Clone() *Board {
+	clone := &Board{Grid: make(map[string]*Piece, len(b.Grid)), Variant: b.Variant, Captured: slices.Clone(b.Captured)}
+	for pos, piece := range b.Grid {
+		if piece == nil {
+			clone.Grid[pos] = nil
+			continue
+		}
+		p := *piece
+		clone.Grid[pos] = &p
+	}
+	return clone
+}
+
+// playerSquares returns the squares occupied by the player pieces, sorted.
+func (b *Board) playerSquares(playerID string) []string {
+	var squares []string
+	for pos, piece := range b.Grid {
+		if piece != nil && piece.PlayerID == playerID {
+			squares = append(squares, pos)
+		}
+	}
+	sort.Strings(squares)
+	return squares
+}
+
+func (b *Board) simpleMoves(pos string) []LegalMove {
+	piece := b.Grid[pos]
+	flying := piece.IsKinged && b.Rules().FlyingKings
+	var moves []LegalMove
+	for _, dir := range b.moveDirections(*piece) {
+		for dist := 1; ; dist++ {
+			dest, ok := b.offsetSquare(pos, dir.rowDelta*dist, dir.colDelta*dist)
+			if !ok || b.Grid[dest] != nil {
+				break
+			}
+			moves = append(moves, LegalMove{PieceID: piece.PieceID, Path: []string{pos, dest}})
+			if !flying {
+				break // Men and short kings only move a single square.
+			}
+		}
+	}
+	return moves
+}
+
+// captureSequences plays out every capture chain available to the piece on pos.
+//
+// Each hop is applied to a copy of the board the same way the gameworker applies it, so the
+// captured piece is removed before the next hop is searched, or kept in Board.Captured with the
+// RemoveCapturedAtEnd rule. A man that lands on the last row keeps capturing as a man, or as a
+// king with the PromoteMidCapture rule, and is promoted if the sequence ends there.
+func (b *Board) captureSequences(pos string) []LegalMove {
+	piece := b.Grid[pos]
+	if piece == nil {
+		return nil
+	}
+	var sequences []LegalMove
+	for _, hop := range b.captureHops(pos) {
+		next := b.Clone()
+		moved := next.Grid[pos]
+		next.Grid[hop.to] = moved
+		next.Grid[pos] = nil
+		if next.Rules().RemoveCapturedAtEnd {
+			next.Captured = append(next.Captured, hop.captured)
+		} else {
+			next.Grid[hop.captured] = nil
+		}
+
+		base := LegalMove{
+			PieceID:  piece.PieceID,
+			Path:     []string{pos, hop.to},
+			Captures: []string{hop.captured},
+		}
+		if !moved.IsKinged && next.Rules().PromoteMidCapture && next.isPromotionSquare(hop.to, *moved) {
+			moved.IsKinged = true
+		}
+		continuations := next.captureSequences(hop.to)
+		if len(continuations) == 0 {
+			sequences = append(sequences, base)
+			continue
+		}
+		for _, c := range continuations {
+			sequences = append(sequences, LegalMove{
+				PieceID:  piece.PieceID,
+				Path:     append([]string{pos}, c.Path...),
+				Captures: append([]string{hop.captured}, c.Captures...),
+			})
+		}
+	}
+	return sequences
+}
+
+type captureHop struct {
+	to       string
+	captured string
+}
+
+// captureHops returns the single captures available to the piece on pos.
+//
+// Men jump an adjacent opponent piece in their forward directions, or in every direction with the
+// MenCaptureBackwards rule, and can't jump kings with the MenCannotTakeKings rule. Flying kings
+// move along the diagonal, jump the first opponent piece they meet and may land on any empty
+// square behind it. Pieces already taken by the sequence block the diagonal and can't be jumped again.
+func (b *Board) captureHops(pos string) []captureHop {
+	piece := b.Grid[pos]
+	flying := piece.IsKinged && b.Rules().FlyingKings
+	var hops []captureHop
+	for _, dir := range b.captureDirections(*piece) {
+		dist := 1
+		midPos, ok := b.offsetSquare(pos, dir.rowDelta, dir.colDelta)
+		if flying {
+			for ok && b.Grid[midPos] == nil {
+				dist++
+				midPos, ok = b.offsetSquare(pos, dir.rowDelta*dist, dir.colDelta*dist)
+			}
+		}
+		if !ok {
+			continue
+		}
+		midPiece := b.Grid[midPos]
+		if midPiece == nil || midPiece.PlayerID == piece.PlayerID || slices.Contains(b.Captured, midPos) {
+			continue
+		}
+		if midPiece.IsKinged && !piece.IsKinged && b.Rules().MenCannotTakeKings {
+			continue
+		}
+		for land := dist + 1; ; land++ {
+			landPos, ok := b.offsetSquare(pos, dir.rowDelta*land, dir.colDelta*land)
+			if !ok || b.Grid[landPos] != nil {
+				break
+			}
+			hops = append(hops, captureHop{to: landPos, captured: midPos})
+			if !flying {
+				break
+			}
+		}
+	}
+	return hops
+}
+
+func (b *Board) captureDirections(piece Piece) []struct{ rowDelta, colDelta int } {
+	if b.Rules().MenCaptureBackwards {
+		return diagonals
+	}
+	return b.moveDirections(piece)
+}
+
+func (b *Board) moveDirections(piece Piece) []struct{ rowDelta, colDelta int } {
+	if piece.IsKinged {
+		return diagonals
+	}
+	direction := GetPieceDirection(piece)
+	return []struct{ rowDelta, colDelta int }{
+		{direction, 1},  // Diagonal right
+		{direction, -1}, // Diagonal left
+	}
+}
+
+// offsetSquare returns the square at the given row and column offset, and if it is on the board.
+func (b *Board) offsetSquare(pos string, rowDelta, colDelta int) (string, bool) {
+	row, col, err := parsePosition(pos)
+	if err != nil {
+		return "", false
+	}
+	square := squareName(row+rune(rowDelta), col+colDelta)
+	if _, _, err := parsePosition(square); err != nil {
+		return "", false
+	}
+	if _, exists := b.Grid[square]; !exists {
+		return "", false
+	}
+	return square, true
+}
+
+// capturedSquare walks the diagonal between from and to and returns the first occupied square.
+func (b *Board) capturedSquare(from, to string) (string, bool) {
+	fromRow, fromCol, err := parsePosition(from)
+	if err != nil {
+		return "", false
+	}
+	toRow, toCol, err := parsePosition(to)
+	if err != nil {
+		return "", false
+	}
+	rowStep, colStep := 1, 1
+	if toRow < fromRow {
+		rowStep = -1
+	}
+	if toCol < fromCol {
+		colStep = -1
+	}
+	for dist := 1; fromRow+rune(rowStep*dist) != toRow; dist++ {
+		square, ok := b.offsetSquare(from, rowStep*dist, colStep*dist)
+		if !ok {
+			return "", false
+		}
+		if b.Grid[square] != nil {
+			return square, true
+		}
+	}
+	return "", false
+}
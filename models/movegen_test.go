@@ -0,0 +1,198 @@
+package models
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const (
+	testBlackID = "black"
+	testWhiteID = "white"
+)
+
+// testBoard parses the FEN with testBlackID and testWhiteID as the players and returns the player to move.
+// An empty fen is the initial position of the variant.
+func testBoard(t *testing.T, fen, variant string) (*Board, string) {
+	t.Helper()
+	if fen == "" {
+		return NewBoard(testBlackID, testWhiteID, variant), testBlackID
+	}
+	board, side, err := ParseFEN(fen, testBlackID, testWhiteID, variant)
+	if err != nil {
+		t.Fatalf("ParseFEN(%q): %v", fen, err)
+	}
+	if side == SideWhite {
+		return board, testWhiteID
+	}
+	return board, testBlackID
+}
+
+// moveNotation writes the moves as sorted paths, e.g. "C3-D4" or "C5xE7xG5".
+func moveNotation(moves []LegalMove) []string {
+	notation := []string{}
+	for _, lm := range moves {
+		separator := "-"
+		if lm.IsCapture() {
+			separator = "x"
+		}
+		notation = append(notation, strings.Join(lm.Path, separator))
+	}
+	sort.Strings(notation)
+	return notation
+}
+
+func TestLegalMoves(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant string
+		fen     string
+		want    []string
+	}{
+		{
+			name:    "initial position",
+			variant: "classic",
+			want:    []string{"C1-D2", "C3-D2", "C3-D4", "C5-D4", "C5-D6", "C7-D6", "C7-D8"},
+		},
+		{
+			name:    "initial position on the 10x10 board",
+			variant: "international",
+			want:    []string{"D10-E9", "D2-E1", "D2-E3", "D4-E3", "D4-E5", "D6-E5", "D6-E7", "D8-E7", "D8-E9"},
+		},
+		{
+			name:    "capture is forced",
+			variant: "classic",
+			fen:     "B:W14:B4,10",
+			want:    []string{"C3xE5"},
+		},
+		{
+			name:    "english men only capture forward",
+			variant: "english",
+			fen:     "B:W14:B18",
+			want:    []string{"E3-F2", "E3-F4"},
+		},
+		{
+			name:    "brazilian men capture backwards",
+			variant: "brazilian",
+			fen:     "B:W14:B18",
+			want:    []string{"E3xC5"},
+		},
+		{
+			name:    "multi-jump is played to the end",
+			variant: "classic",
+			fen:     MultipleCaptureTestFEN,
+			want:    []string{"C5xE7xG5"},
+		},
+		{
+			name:    "flying king lands on any square behind the piece",
+			variant: "classic",
+			fen:     "B:W14:BK1",
+			want:    []string{"A1xE5", "A1xF6", "A1xG7", "A1xH8"},
+		},
+		{
+			name:    "english kings do not fly",
+			variant: "english",
+			fen:     "B:W14:BK1",
+			want:    []string{"A1-B2"},
+		},
+		{
+			name:    "shorter capture allowed without the max capture rule",
+			variant: "english",
+			fen:     "B:W13,15,23:B9,11",
+			want:    []string{"C1xE3", "C5xE7xG5"},
+		},
+		{
+			name:    "brazilian must take the most pieces",
+			variant: "brazilian",
+			fen:     "B:W13,15,23:B9,11",
+			want:    []string{"C5xE7xG5"},
+		},
+		{
+			name:    "russian man promoted mid capture goes on as a king",
+			variant: "russian",
+			fen:     "B:W23,26:B21",
+			want:    []string{"F2xH4xD8", "F2xH4xE7"},
+		},
+		{
+			name:    "brazilian man on the last row mid capture goes on as a man",
+			variant: "brazilian",
+			fen:     "B:W23,26:B21",
+			want:    []string{"F2xH4"},
+		},
+		{
+			name:    "classic removes each captured piece before the next hop",
+			variant: "classic",
+			fen:     "B:W5,23:BK10",
+			want:    []string{"C3xA1xG7", "C3xA1xH8", "C3xG7xA1", "C3xH8xA1"},
+		},
+		{
+			name:    "brazilian captured pieces block until the sequence ends",
+			variant: "brazilian",
+			fen:     "B:W5,23:BK10",
+			want:    []string{"C3xA1", "C3xG7", "C3xH8"},
+		},
+		{
+			name:    "italian men cannot take kings",
+			variant: "italian",
+			fen:     "B:WK14:B10",
+			want:    []string{"C3-D2"},
+		},
+		{
+			name:    "italian king must capture",
+			variant: "italian",
+			fen:     "B:W13,15:BK9,11",
+			want:    []string{"C1xE3"},
+		},
+		{
+			name:    "english men and kings capture alike",
+			variant: "english",
+			fen:     "B:W13,15:BK9,11",
+			want:    []string{"C1xE3", "C5xE7"},
+		},
+		{
+			name:    "italian king must take the most kings",
+			variant: "italian",
+			fen:     "B:WK5,13:BK9",
+			want:    []string{"C1xA3"},
+		},
+		{
+			name:    "blocked player has no moves",
+			variant: "classic",
+			fen:     "B:W13,18:B9",
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, playerID := testBoard(t, tt.fen, tt.variant)
+			got := moveNotation(board.LegalMoves(playerID))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("LegalMoves() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegalMovesCapturedPieces(t *testing.T) {
+	board, playerID := testBoard(t, MultipleCaptureTestFEN, "classic")
+	moves := board.LegalMoves(playerID)
+	if len(moves) != 1 {
+		t.Fatalf("LegalMoves() returned %d moves, want 1", len(moves))
+	}
+	if want := []string{"D6", "F6"}; !slices.Equal(moves[0].Captures, want) {
+		t.Errorf("Captures = %v, want %v", moves[0].Captures, want)
+	}
+	if moves[0].PieceID != board.Grid["C5"].PieceID {
+		t.Errorf("PieceID = %s, want the piece on C5", moves[0].PieceID)
+	}
+}
+
+func TestLegalMovesDoNotChangeTheBoard(t *testing.T) {
+	board, playerID := testBoard(t, "B:W23,26:B21", "russian")
+	before := board.FEN(SideBlack)
+	board.LegalMoves(playerID)
+	if after := board.FEN(SideBlack); after != before {
+		t.Errorf("board changed from %s to %s", before, after)
+	}
+}
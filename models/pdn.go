@@ -0,0 +1,410 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PDN GameType tag of each variant. Classic has no standard number, 21 is english checkers without
+// flying kings, so its games are exported with our Variant tag only.
+var pdnGameTypes = map[string]int{
+	"international": 20,
+	"english":       21,
+	"italian":       22,
+	"russian":       25,
+	"brazilian":     26,
+}
+
+// PDN results are written with the draughts scores, the white score first.
+const (
+	PDNWhiteWins = "2-0"
+	PDNBlackWins = "0-2"
+	PDNDraw      = "1-1"
+	PDNUnknown   = "*"
+	PDNPass      = "--" // A turn the player lost on a timeout without moving.
+)
+
+// pdnResults maps the results written by other software to ours. English checkers tools (GameType 21)
+// score a win as 1 and give the score of black, the side that opens, first.
+var pdnResults = map[string]string{
+	PDNWhiteWins: PDNWhiteWins,
+	PDNBlackWins: PDNBlackWins,
+	PDNDraw:      PDNDraw,
+	"1-0":        PDNBlackWins,
+	"0-1":        PDNWhiteWins,
+	"1/2-1/2":    PDNDraw,
+}
+
+var (
+	pdnTagRegex        = regexp.MustCompile(`\[(\w+)\s+"([^"]*)"\]`)
+	pdnCommentRegex    = regexp.MustCompile(`\{[^}]*\}|\([^)]*\)`)
+	pdnMoveNumberRegex = regexp.MustCompile(`^\d+\.+`)
+)
+
+// ExportPDN writes the game in Portable Draughts Notation, squares are numbered from 1 on the black side
+// of the board, row by row. The reason is the game over reason stored with the game.
+func ExportPDN(game Game, reason string) string {
+	var sb strings.Builder
+	writeTag := func(name, value string) {
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, strings.ReplaceAll(value, `"`, `'`))
+	}
+	variant := GetRuleSet(game.Variant).Name
+	writeTag("Event", game.OperatorIdentifier.GameName)
+	writeTag("Site", game.OperatorIdentifier.OperatorName)
+	writeTag("Date", game.StartTime.UTC().Format("2006.01.02"))
+	writeTag("Time", game.StartTime.UTC().Format("15:04:05"))
+	writeTag("Round", "-")
+	writeTag("Black", game.playerNameByColor("b"))
+	writeTag("White", game.playerNameByColor("w"))
+	writeTag("Result", game.pdnResult())
+	if gameType, ok := pdnGameTypes[variant]; ok {
+		writeTag("GameType", strconv.Itoa(gameType))
+	}
+	writeTag("Variant", variant)
+	if game.StartFEN != "" {
+		writeTag("FEN", game.StartFEN)
+	}
+	writeTag("GameID", game.ID)
+	if !game.EndTime.IsZero() {
+		writeTag("EndDate", game.EndTime.UTC().Format("2006.01.02"))
+		writeTag("EndTime", game.EndTime.UTC().Format("15:04:05"))
+	}
+	if reason != "" {
+		writeTag("Termination", reason)
+	}
+	sb.WriteString("\n")
+
+	position := Position{Rules: GetRuleSet(variant)}
+	lineLength := 0
+	moveNumber := 1
+	for i, turn := range game.pdnTurns() {
+		token := PDNPass
+		if len(turn.hops) > 0 {
+			token = pdnMove(position, turn.hops) + pdnClock(turn.hops)
+		}
+		switch {
+		case turn.color == "b":
+			token = fmt.Sprintf("%d. %s", moveNumber, token)
+		case i == 0:
+			token = fmt.Sprintf("%d... %s", moveNumber, token)
+		}
+		if turn.color == "w" {
+			moveNumber++
+		}
+		if lineLength > 0 && lineLength+len(token) > 79 {
+			sb.WriteString("\n")
+			lineLength = 0
+		} else if lineLength > 0 {
+			sb.WriteString(" ")
+			lineLength++
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	if lineLength > 0 {
+		sb.WriteString(" ")
+	}
+	sb.WriteString(game.pdnResult())
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// pdnTurn is a turn of the game, the hops of the move or none when the player passed.
+type pdnTurn struct {
+	color string
+	hops  []Move
+}
+
+// pdnTurns joins the hops of each capture sequence, the gameworker stores each hop as its own Move,
+// and adds a pass for the turns the player lost on a timeout without moving.
+func (g *Game) pdnTurns() []pdnTurn {
+	var turns []pdnTurn
+	color := strings.ToLower(g.StartSide().String())
+	for i, move := range g.Moves {
+		if i > 0 && g.continuesTurn(g.Moves[i-1], move) {
+			turns[len(turns)-1].hops = append(turns[len(turns)-1].hops, move)
+			continue
+		}
+		moverColor := g.playerColor(move.PlayerID)
+		if moverColor != color {
+			turns = append(turns, pdnTurn{color: color})
+		}
+		turns = append(turns, pdnTurn{color: moverColor, hops: []Move{move}})
+		color = opponentColor(moverColor)
+	}
+	return turns
+}
+
+// continuesTurn reports if the hop goes on with the capture sequence of the previous one. The FEN stored
+// with a hop starts with the side to move after it, the mover's side while the sequence goes on.
+func (g *Game) continuesTurn(prev, move Move) bool {
+	if prev.PlayerID != move.PlayerID || !prev.IsCapture || !move.IsCapture || prev.To != move.From {
+		return false
+	}
+	return prev.FEN == "" || strings.EqualFold(prev.FEN[:1], g.playerColor(move.PlayerID))
+}
+
+// pdnMove writes a turn with numeric squares, e.g. "9-13" or "22x15x6".
+func pdnMove(position Position, turn []Move) string {
+	separator := "-"
+	if turn[0].IsCapture {
+		separator = "x"
+	}
+	squares := []string{pdnSquare(position, turn[0].From)}
+	for _, hop := range turn {
+		squares = append(squares, pdnSquare(position, hop.To))
+	}
+	return strings.Join(squares, separator)
+}
+
+// pdnClock writes the timing of a turn as a comment, the clock left after the move and the think time,
+// e.g. " {[%clk 0:04:12] [%emt 0:00:03.2]}". Empty for games stored before moves were timed.
+func pdnClock(turn []Move) string {
+	last := turn[len(turn)-1]
+	if last.ReceivedAt.IsZero() {
+		return ""
+	}
+	clock := time.Duration(last.ClockRemaining) * time.Second
+	think := time.Duration(last.ThinkTime) * time.Millisecond
+	return fmt.Sprintf(" {[%%clk %d:%02d:%02.0f] [%%emt %d:%02d:%04.1f]}",
+		int(clock.Hours()), int(clock.Minutes())%60, (clock % time.Minute).Seconds(),
+		int(think.Hours()), int(think.Minutes())%60, (think % time.Minute).Seconds(),
+	)
+}
+
+func pdnSquare(position Position, pos string) string {
+	sq, ok := position.SquareIndex(pos)
+	if !ok {
+		return pos
+	}
+	return strconv.Itoa(sq + 1)
+}
+
+func (g *Game) playerNameByColor(color string) string {
+	for _, player := range g.Players {
+		if player.Color == color {
+			return player.Name
+		}
+	}
+	return "?"
+}
+
+func (g *Game) playerColor(playerID string) string {
+	for _, player := range g.Players {
+		if player.ID == playerID {
+			return player.Color
+		}
+	}
+	return ""
+}
+
+func opponentColor(color string) string {
+	if color == "w" {
+		return "b"
+	}
+	return "w"
+}
+
+func (g *Game) playerIDByColor(color string) string {
+	for _, player := range g.Players {
+		if player.Color == color {
+			return player.ID
+		}
+	}
+	return ""
+}
+
+func (g *Game) pdnResult() string {
+	switch {
+	case g.EndTime.IsZero():
+		return PDNUnknown
+	case g.IsDraw():
+		return PDNDraw
+	case g.Winner == "":
+		return PDNUnknown
+	case g.Winner == g.playerIDByColor("w"):
+		return PDNWhiteWins
+	default:
+		return PDNBlackWins
+	}
+}
+
+// ImportPDN rebuilds a game from PDN, every move is checked and played through the rules engine.
+//
+// The players get new IDs, the names come from the Black and White tags. The variant is read from
+// our Variant tag, or from the GameType tag for games exported by other software, and the game starts
+// from the FEN tag when there is one.
+func ImportPDN(pdn string) (*Game, error) {
+	tags := map[string]string{}
+	for _, match := range pdnTagRegex.FindAllStringSubmatch(pdn, -1) {
+		tags[match[1]] = match[2]
+	}
+	variant, err := pdnVariant(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	blackID, whiteID := uuid.New().String(), uuid.New().String()
+	gameID := tags["GameID"]
+	if gameID == "" {
+		gameID = uuid.New().String()
+	}
+	game := &Game{
+		ID:      gameID,
+		Board:   *NewBoard(blackID, whiteID, variant),
+		Variant: variant,
+		Players: []GamePlayer{
+			{ID: blackID, Name: tags["Black"], Color: "b"},
+			{ID: whiteID, Name: tags["White"], Color: "w"},
+		},
+		CurrentPlayerID: blackID,
+		Moves:           []Move{},
+		PositionCounts:  map[string]int{},
+		StartTime:       parsePDNTime(tags["Date"], tags["Time"]),
+	}
+	if fen := tags["FEN"]; fen != "" {
+		board, sideToMove, err := ParseFEN(fen, blackID, whiteID, variant)
+		if err != nil {
+			return nil, fmt.Errorf("(ImportPDN) - invalid FEN tag: %w", err)
+		}
+		game.Board, game.StartFEN = *board, board.FEN(sideToMove)
+		if sideToMove == SideWhite {
+			game.CurrentPlayerID = whiteID
+		}
+	}
+	game.UpdatePlayerPieces()
+
+	movetext := pdnTagRegex.ReplaceAllString(pdn, "")
+	movetext = pdnCommentRegex.ReplaceAllString(movetext, " ")
+	result := PDNUnknown
+	gameOver := false
+	for _, token := range strings.Fields(movetext) {
+		token = pdnMoveNumberRegex.ReplaceAllString(token, "")
+		token = strings.TrimRight(token, "!?*+")
+		if token == "" {
+			continue
+		}
+		if pdnResult, ok := pdnResults[token]; ok {
+			result = pdnResult
+			continue
+		}
+		if gameOver {
+			return nil, fmt.Errorf("(ImportPDN) - move %s after the end of the game", token)
+		}
+		if token == PDNPass {
+			game.passTurn() // The player lost the turn on a timeout.
+			continue
+		}
+		if err := game.playPDNMove(token); err != nil {
+			return nil, err
+		}
+		if game.CheckGameOver() {
+			gameOver = true
+			result = PDNBlackWins
+			if game.CurrentPlayerID == whiteID {
+				result = PDNWhiteWins
+			}
+			continue
+		}
+		game.passTurn()
+	}
+	if pdnResult, ok := pdnResults[tags["Result"]]; ok && !gameOver {
+		result = pdnResult
+	}
+	game.applyPDNResult(result, parsePDNTime(tags["EndDate"], tags["EndTime"]))
+	return game, nil
+}
+
+func pdnVariant(tags map[string]string) (string, error) {
+	if variant, ok := tags["Variant"]; ok && IsValidVariant(variant) {
+		return variant, nil
+	}
+	gameType, ok := tags["GameType"]
+	if !ok {
+		return DefaultVariant, nil
+	}
+	number, err := strconv.Atoi(strings.Split(gameType, ",")[0])
+	if err != nil {
+		return "", fmt.Errorf("(ImportPDN) - invalid GameType tag: %s", gameType)
+	}
+	for variant, variantNumber := range pdnGameTypes {
+		if variantNumber == number {
+			return variant, nil
+		}
+	}
+	return "", fmt.Errorf("(ImportPDN) - unsupported GameType: %d", number)
+}
+
+// playPDNMove finds the legal move written in the token and plays it, the turn is changed by the caller.
+// Captures can list every landing square or only the origin and the destination, as long as that is not ambiguous.
+func (g *Game) playPDNMove(token string) error {
+	position := g.Board.Position()
+	var path []string
+	for _, number := range strings.FieldsFunc(token, func(r rune) bool { return r == '-' || r == 'x' || r == ':' }) {
+		sq, err := strconv.Atoi(number)
+		if err != nil || sq < 1 || sq > len(position.geometry().names) {
+			return fmt.Errorf("(ImportPDN) - invalid move %s, turn %d", token, g.Turn+1)
+		}
+		path = append(path, position.SquareName(sq-1))
+	}
+	if len(path) < 2 {
+		return fmt.Errorf("(ImportPDN) - invalid move %s, turn %d", token, g.Turn+1)
+	}
+
+	var matches []LegalMove
+	for _, lm := range g.LegalMoves() {
+		if slices.Equal(lm.Path, path) || (len(path) == 2 && lm.From() == path[0] && lm.To() == path[1]) {
+			matches = append(matches, lm)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("(ImportPDN) - illegal move %s, turn %d", token, g.Turn+1)
+	}
+	if len(matches) > 1 {
+		return fmt.Errorf("(ImportPDN) - ambiguous move %s, turn %d, write every landing square", token, g.Turn+1)
+	}
+	for _, hop := range matches[0].Hops(g.CurrentPlayerID) {
+		if _, err := g.PlayHop(hop); err != nil {
+			return fmt.Errorf("(ImportPDN) - move %s, turn %d: %w", token, g.Turn+1, err)
+		}
+	}
+	return nil
+}
+
+func (g *Game) applyPDNResult(result string, endTime time.Time) {
+	switch result {
+	case PDNWhiteWins:
+		g.Winner = g.playerIDByColor("w")
+	case PDNBlackWins:
+		g.Winner = g.playerIDByColor("b")
+	case PDNDraw:
+		g.Winner = ""
+		g.DrawRule = "pdn_result" // PDN does not say which rule ended the game.
+	default:
+		return
+	}
+	g.EndTime = endTime
+	if g.EndTime.IsZero() {
+		g.EndTime = g.StartTime
+	}
+}
+
+func parsePDNTime(date, clock string) time.Time {
+	if date == "" {
+		return time.Time{}
+	}
+	if clock == "" {
+		clock = "00:00:00"
+	}
+	t, err := time.Parse("2006.01.02 15:04:05", date+" "+clock)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
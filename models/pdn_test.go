@@ -0,0 +1,190 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// playTurn plays the legal move with the path and passes the turn, like the gameworker does.
+func playTurn(t *testing.T, game *Game, path ...string) {
+	t.Helper()
+	lm, ok := findPath(game.LegalMoves(), path)
+	if !ok {
+		t.Fatalf("%v is not a legal move of %s", path, game.CurrentPlayerID)
+	}
+	for _, hop := range lm.Hops(game.CurrentPlayerID) {
+		if _, err := game.PlayHop(hop); err != nil {
+			t.Fatalf("PlayHop(%s-%s): %v", hop.From, hop.To, err)
+		}
+	}
+	game.NextPlayer()
+}
+
+func TestExportPDNWithPass(t *testing.T) {
+	game := testGame(t, "", "classic")
+	playTurn(t, game, "C3", "D4")
+	playTurn(t, game, "F4", "E3")
+	game.NextPlayer() // Black lost the turn on a timeout.
+	playTurn(t, game, "G3", "F4")
+	playTurn(t, game, "C5", "D6")
+
+	pdn := ExportPDN(*game, "")
+	if want := "\n1. 10-14 22-18 2. -- 26-22 3. 11-15 *\n"; !strings.HasSuffix(pdn, want) {
+		t.Errorf("ExportPDN() movetext = %q, want %q", pdn[strings.LastIndex(pdn, "]")+1:], want)
+	}
+
+	imported, err := ImportPDN(pdn)
+	if err != nil {
+		t.Fatalf("ImportPDN: %v", err)
+	}
+	if got, want := pdnHops(imported), pdnHops(game); !slices.Equal(got, want) {
+		t.Errorf("imported moves %v, want %v", got, want)
+	}
+	if imported.CurrentPlayerID != imported.playerIDByColor("w") {
+		t.Errorf("imported game has black to move, want white")
+	}
+}
+
+func TestExportPDNClock(t *testing.T) {
+	game := testGame(t, "", "classic")
+	playTurn(t, game, "C3", "D4")
+	game.Moves[0].ReceivedAt = time.Date(2025, 3, 1, 12, 0, 5, 0, time.UTC)
+	game.Moves[0].ClockRemaining = 252
+	game.Moves[0].ThinkTime = 3200
+	playTurn(t, game, "F4", "E3") // Stored before moves were timed.
+
+	pdn := ExportPDN(*game, "")
+	if want := "\n1. 10-14 {[%clk 0:04:12] [%emt 0:00:03.2]} 22-18 *\n"; !strings.HasSuffix(pdn, want) {
+		t.Errorf("ExportPDN() movetext = %q, want %q", pdn[strings.LastIndex(pdn, "]\n")+2:], want)
+	}
+}
+
+func TestPDNRoundTrip(t *testing.T) {
+	game := testGame(t, "", "english")
+	playTurn(t, game, "C3", "D4")
+	playTurn(t, game, "F6", "E5")
+	playTurn(t, game, "D4", "F6")
+	playTurn(t, game, "G7", "E5")
+	playTurn(t, game, "C5", "D6")
+	game.FinishGameAsDraw("agreement")
+
+	pdn := ExportPDN(*game, "draw")
+	for _, want := range []string{`[Result "1-1"]`, `[GameType "21"]`, `[Termination "draw"]`, "1. 10-14 23-19 2. 14x23 28x19 3. 11-15 1-1"} {
+		if !strings.Contains(pdn, want) {
+			t.Errorf("ExportPDN() has no %s:\n%s", want, pdn)
+		}
+	}
+	imported, err := ImportPDN(pdn)
+	if err != nil {
+		t.Fatalf("ImportPDN: %v", err)
+	}
+	if got, want := pdnHops(imported), pdnHops(game); !slices.Equal(got, want) {
+		t.Errorf("imported moves %v, want %v", got, want)
+	}
+	if !imported.IsDraw() || imported.Variant != "english" {
+		t.Errorf("imported IsDraw() = %v, Variant = %s, want a draw of english", imported.IsDraw(), imported.Variant)
+	}
+	if got, want := imported.Board.FEN(SideWhite), game.Board.FEN(SideWhite); got != want {
+		t.Errorf("imported board %s, want %s", got, want)
+	}
+	// An imported game was not played here, its turns don't run the clocks.
+	if !imported.TurnStartedAt.IsZero() || imported.Players[0].Timer != 0 || imported.Players[1].Timer != 0 {
+		t.Errorf("imported TurnStartedAt = %v, timers %d and %d, want no clock", imported.TurnStartedAt, imported.Players[0].Timer, imported.Players[1].Timer)
+	}
+}
+
+func TestPDNTurns(t *testing.T) {
+	game := testGame(t, "", "classic")
+	game.Moves = []Move{
+		{PlayerID: testBlackID, From: "C1", To: "E3", IsCapture: true, FEN: "B:W..."},
+		{PlayerID: testBlackID, From: "E3", To: "G5", IsCapture: true, FEN: "W:W..."},
+		// White passed, black captures again with the same piece.
+		{PlayerID: testBlackID, From: "G5", To: "E7", IsCapture: true, FEN: "W:W..."},
+		{PlayerID: testWhiteID, From: "F2", To: "E1", FEN: "B:W..."},
+	}
+	var got []string
+	for _, turn := range game.pdnTurns() {
+		got = append(got, turn.color+":"+strings.Repeat("h", len(turn.hops)))
+	}
+	if want := []string{"b:hh", "w:", "b:h", "w:h"}; !slices.Equal(got, want) {
+		t.Errorf("pdnTurns() = %v, want %v", got, want)
+	}
+}
+
+// pdnHops lists the hops of the game with the colour of the mover, the player IDs change on import.
+func pdnHops(game *Game) []string {
+	var hops []string
+	for _, move := range game.Moves {
+		hops = append(hops, game.playerColor(move.PlayerID)+":"+move.From+"-"+move.To)
+	}
+	return hops
+}
+
+func TestPDNStartFEN(t *testing.T) {
+	game := testGame(t, "W:W21,K30:B9,14", "classic")
+	game.StartFEN = "W:W21,K30:B9,14"
+	playTurn(t, game, "F2", "E1")
+	playTurn(t, game, "D4", "E5")
+
+	pdn := ExportPDN(*game, "")
+	for _, want := range []string{`[FEN "W:W21,K30:B9,14"]`, "1... 21-17 2. 14-19 *"} {
+		if !strings.Contains(pdn, want) {
+			t.Errorf("ExportPDN() has no %s:\n%s", want, pdn)
+		}
+	}
+	imported, err := ImportPDN(pdn)
+	if err != nil {
+		t.Fatalf("ImportPDN: %v", err)
+	}
+	if imported.StartFEN != game.StartFEN {
+		t.Errorf("imported StartFEN = %s, want %s", imported.StartFEN, game.StartFEN)
+	}
+	if got, want := pdnHops(imported), pdnHops(game); !slices.Equal(got, want) {
+		t.Errorf("imported moves %v, want %v", got, want)
+	}
+}
+
+func TestImportPDNEnglishResult(t *testing.T) {
+	pdn := `[Black "Tinsley"]
+[White "Chinook"]
+[Date "1992.08.17"]
+[GameType "21"]
+[Result "1-0"]
+
+1. 10-14 23-19 2. 14x23 28x19 1-0
+`
+	game, err := ImportPDN(pdn)
+	if err != nil {
+		t.Fatalf("ImportPDN: %v", err)
+	}
+	if game.Variant != "english" {
+		t.Errorf("imported Variant = %s, want english", game.Variant)
+	}
+	if game.Winner != game.playerIDByColor("b") || len(game.Moves) != 4 {
+		t.Errorf("imported Winner = %s with %d moves, want black with 4", game.Winner, len(game.Moves))
+	}
+
+	game, err = ImportPDN(strings.ReplaceAll(pdn, "1-0", "1/2-1/2"))
+	if err != nil {
+		t.Fatalf("ImportPDN: %v", err)
+	}
+	if !game.IsDraw() {
+		t.Errorf("imported 1/2-1/2 IsDraw() = false, want a draw")
+	}
+}
+
+func TestExportPDNClassicGameType(t *testing.T) {
+	pdn := ExportPDN(*testGame(t, "", "classic"), "")
+	if strings.Contains(pdn, "[GameType") || !strings.Contains(pdn, `[Variant "classic"]`) {
+		t.Errorf("ExportPDN() of classic wants no GameType and the Variant tag:\n%s", pdn)
+	}
+	imported, err := ImportPDN(strings.ReplaceAll(pdn, `[Variant "classic"]`, `[GameType "21"]`))
+	if err != nil {
+		t.Fatalf("ImportPDN: %v", err)
+	}
+	if imported.Variant != "english" {
+		t.Errorf("GameType 21 imported as %s, want english", imported.Variant)
+	}
+}
@@ -33,6 +33,23 @@ type Player struct {
 	WriteChan          chan []byte        `json:"-"` // Channel for serialized writes
 	OperatorIdentifier OperatorIdentifier `json:"operator_identifier"`
 	DisconnectedAt     int64              `json:"disconnected_at"` // Unix timestamp
+	IsBot              bool               `json:"is_bot,omitempty"`
+	BotDifficulty      string             `json:"bot_difficulty,omitempty"` // For bots the level they play at, for players the level of the bot they asked to play against.
+}
+
+// NewBotPlayer creates a bot to play against the player, at the same bet and operator.
+// Bots have no session, they never go through the operator wallet.
+func NewBotPlayer(opponent *Player, difficulty string) *Player {
+	return &Player{
+		ID:                 GenerateUUID(), // Games store the winner ID as a UUID.
+		Name:               "Bot",
+		Currency:           opponent.Currency,
+		Status:             StatusInQueue,
+		SelectedBet:        opponent.SelectedBet,
+		OperatorIdentifier: opponent.OperatorIdentifier,
+		IsBot:              true,
+		BotDifficulty:      difficulty,
+	}
 }
 
 func (p *Player) StartWriteGoroutine(onClose func()) {
@@ -87,6 +104,22 @@ func (p *Player) StartWriteGoroutineNew(onClose func(), pingInterval time.Durati
 
 var DamasValidBetAmounts = []float64{0.5, 1, 3, 5, 10, 25, 50, 100}
 
+// PracticeBetValue is the queue of the practice games, they are played for free and never reach the operator wallet.
+const PracticeBetValue float64 = 0
+
+// IsValidBet reports if the player can queue with the bet, a valid bet amount or the practice queue.
+func IsValidBet(bet float64) bool {
+	if bet == PracticeBetValue {
+		return true
+	}
+	for _, v := range DamasValidBetAmounts {
+		if v == bet {
+			return true
+		}
+	}
+	return false
+}
+
 // This map will hold the valid status transition
 var validStatusTransitions = map[PlayerStatus]map[PlayerStatus]bool{
 	StatusOffline: {
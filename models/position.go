@@ -0,0 +1,413 @@
+package models
+
+import (
+	"math/bits"
+
+	"github.com/google/uuid"
+)
+
+// Side is the colour of a player pieces, black moves down the board and starts the game.
+type Side uint8
+
+const (
+	SideBlack Side = iota
+	SideWhite
+)
+
+func (s Side) Opponent() Side {
+	return s ^ 1
+}
+
+// Position is a compact copy of a Board for the rules engine, bots, analysis and replays.
+//
+// Only the playable squares are stored, square i of the board is bit i of the bitboards, numbered
+// row by row from A. The move generator follows the same rules as Board.LegalMoves without the
+// string keys and piece IDs, convert back with Position.Grid to send a position to the clients.
+type Position struct {
+	Rules RuleSet
+	Black uint64 // Squares with a black piece.
+	White uint64 // Squares with a white piece.
+	Kings uint64 // Squares with a king, of either side.
+	Taken uint64 // Pieces taken by the capture sequence in progress, still on the board, see Board.Captured.
+}
+
+// PositionMove is a move of the rules engine, squares are the bitboard indexes.
+type PositionMove struct {
+	Path     []int // e.g. [9, 18, 25]
+	Captures []int // Captured squares, Path[i+1] is reached by taking Captures[i].
+	Kings    int   // Kings taken by the move, for the king priority rule.
+}
+
+func (m PositionMove) IsCapture() bool {
+	return len(m.Captures) > 0
+}
+
+func (m PositionMove) From() int {
+	return m.Path[0]
+}
+
+func (m PositionMove) To() int {
+	return m.Path[len(m.Path)-1]
+}
+
+// geometry holds the square numbering and the diagonal rays of a board size.
+type geometry struct {
+	names []string       // Square name of each index.
+	index map[string]int // Index of each playable square name.
+	rows  []int          // Row of each index, 0 is A.
+	rays  [][4][]int     // Squares along each of the diagonals, nearest first.
+}
+
+var geometries = map[int]*geometry{}
+
+func init() {
+	for _, rules := range RuleSets {
+		if _, ok := geometries[rules.BoardSize]; !ok {
+			geometries[rules.BoardSize] = newGeometry(rules.BoardSize)
+		}
+	}
+}
+
+func newGeometry(size int) *geometry {
+	g := &geometry{index: map[string]int{}}
+	coords := map[[2]int]int{}
+	for row := 0; row < size; row++ {
+		for col := 1; col <= size; col++ {
+			if (row+col)%2 == 1 { // Same dark squares as GenerateInitialBoard.
+				coords[[2]int{row, col}] = len(g.names)
+				g.index[squareName('A'+rune(row), col)] = len(g.names)
+				g.names = append(g.names, squareName('A'+rune(row), col))
+				g.rows = append(g.rows, row)
+			}
+		}
+	}
+	g.rays = make([][4][]int, len(g.names))
+	for coord, sq := range coords {
+		for d, dir := range diagonals {
+			for dist := 1; ; dist++ {
+				next, ok := coords[[2]int{coord[0] + dir.rowDelta*dist, coord[1] + dir.colDelta*dist}]
+				if !ok {
+					break
+				}
+				g.rays[sq][d] = append(g.rays[sq][d], next)
+			}
+		}
+	}
+	return g
+}
+
+func (p *Position) geometry() *geometry {
+	return geometries[p.Rules.BoardSize]
+}
+
+// Position converts the board to the compact representation.
+func (b *Board) Position() Position {
+	p := Position{Rules: b.Rules()}
+	g := p.geometry()
+	for pos, piece := range b.Grid {
+		sq, ok := g.index[pos]
+		if piece == nil || !ok {
+			continue
+		}
+		bit := uint64(1) << sq
+		if piece.Type == "w" {
+			p.White |= bit
+		} else {
+			p.Black |= bit
+		}
+		if piece.IsKinged {
+			p.Kings |= bit
+		}
+	}
+	for _, pos := range b.Captured {
+		if sq, ok := g.index[pos]; ok {
+			p.Taken |= uint64(1) << sq
+		}
+	}
+	return p
+}
+
+func PieceSide(piece Piece) Side {
+	if piece.Type == "w" {
+		return SideWhite
+	}
+	return SideBlack
+}
+
+// SideOf returns the side of the player pieces, and false if the player has no piece on the board.
+func (b *Board) SideOf(playerID string) (Side, bool) {
+	for _, piece := range b.Grid {
+		if piece != nil && piece.PlayerID == playerID {
+			return PieceSide(*piece), true
+		}
+	}
+	return SideBlack, false
+}
+
+// Grid converts the position back to the JSON grid, the pieces get new IDs.
+func (p *Position) Grid(blackID, whiteID string) map[string]*Piece {
+	size := p.Rules.BoardSize
+	grid := make(map[string]*Piece, size*size)
+	for row := 'A'; row < 'A'+rune(size); row++ {
+		for col := 1; col <= size; col++ {
+			grid[squareName(row, col)] = nil
+		}
+	}
+	for sq, name := range p.geometry().names {
+		bit := uint64(1) << sq
+		switch {
+		case p.Black&bit != 0:
+			grid[name] = &Piece{Type: "b", PieceID: uuid.New().String(), PlayerID: blackID, IsKinged: p.Kings&bit != 0}
+		case p.White&bit != 0:
+			grid[name] = &Piece{Type: "w", PieceID: uuid.New().String(), PlayerID: whiteID, IsKinged: p.Kings&bit != 0}
+		}
+	}
+	return grid
+}
+
+// SquareName returns the board square of a bitboard index, e.g. 0 → "A2".
+func (p *Position) SquareName(sq int) string {
+	return p.geometry().names[sq]
+}
+
+// SquareIndex returns the bitboard index of a board square, and false if it is not a playable square.
+func (p *Position) SquareIndex(pos string) (int, bool) {
+	sq, ok := p.geometry().index[pos]
+	return sq, ok
+}
+
+// Row returns the row of a bitboard index, 0 is row A where the black pieces start.
+func (p *Position) Row(sq int) int {
+	return p.geometry().rows[sq]
+}
+
+func (p *Position) pieces(side Side) uint64 {
+	if side == SideWhite {
+		return p.White
+	}
+	return p.Black
+}
+
+// Count returns the number of pieces and kings of the side.
+func (p *Position) Count(side Side) (pieces, kings int) {
+	own := p.pieces(side)
+	return bits.OnesCount64(own), bits.OnesCount64(own & p.Kings)
+}
+
+// LegalMoves returns every legal move for the side, same rules as Board.LegalMoves, sorted by origin square index.
+func (p *Position) LegalMoves(side Side) []PositionMove {
+	var captures, simple []PositionMove
+	for own := p.pieces(side); own != 0; own &= own - 1 {
+		sq := bits.TrailingZeros64(own)
+		captures = p.appendCaptures(captures, side, sq)
+		if len(captures) == 0 {
+			simple = p.appendSimpleMoves(simple, side, sq)
+		}
+	}
+	if len(captures) == 0 {
+		return simple
+	}
+	return p.filterCaptures(captures)
+}
+
+// CapturesFrom returns the capture sequences of the piece on sq only, for the continuation of a capture sequence.
+func (p *Position) CapturesFrom(side Side, sq int) []PositionMove {
+	captures := p.appendCaptures(nil, side, sq)
+	if len(captures) == 0 {
+		return nil
+	}
+	return p.filterCaptures(captures)
+}
+
+func (p *Position) filterCaptures(captures []PositionMove) []PositionMove {
+	if !p.Rules.MandatoryMaxCapture {
+		return captures
+	}
+	captures = keepBestPositionMoves(captures, func(m PositionMove) int { return len(m.Captures) })
+	if !p.Rules.KingCapturePriority {
+		return captures
+	}
+	captures = keepBestPositionMoves(captures, func(m PositionMove) int {
+		if p.Kings&(uint64(1)<<m.From()) != 0 {
+			return 1
+		}
+		return 0
+	})
+	return keepBestPositionMoves(captures, func(m PositionMove) int { return m.Kings })
+}
+
+func keepBestPositionMoves(moves []PositionMove, score func(PositionMove) int) []PositionMove {
+	best := 0
+	for _, m := range moves {
+		if s := score(m); s > best {
+			best = s
+		}
+	}
+	kept := moves[:0]
+	for _, m := range moves {
+		if score(m) == best {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// Directions of the diagonals in the same order as diagonals, black men move down with the first two.
+func (p *Position) moveDirections(side Side, king bool) []int {
+	switch {
+	case king:
+		return []int{0, 1, 2, 3}
+	case side == SideBlack:
+		return []int{0, 1}
+	default:
+		return []int{2, 3}
+	}
+}
+
+func (p *Position) appendSimpleMoves(moves []PositionMove, side Side, sq int) []PositionMove {
+	occupied := p.Black | p.White
+	king := p.Kings&(uint64(1)<<sq) != 0
+	flying := king && p.Rules.FlyingKings
+	rays := p.geometry().rays[sq]
+	for _, d := range p.moveDirections(side, king) {
+		for _, dest := range rays[d] {
+			if occupied&(uint64(1)<<dest) != 0 {
+				break
+			}
+			moves = append(moves, PositionMove{Path: []int{sq, dest}})
+			if !flying {
+				break // Men and short kings only move a single square.
+			}
+		}
+	}
+	return moves
+}
+
+// appendCaptures plays out the capture chains of the piece on sq, removing each captured piece before
+// the next hop the same way Board.captureSequences does. With the RemoveCapturedAtEnd rule the captured
+// pieces are only taken out of the opponents, they still block the diagonals.
+func (p *Position) appendCaptures(moves []PositionMove, side Side, sq int) []PositionMove {
+	king := p.Kings&(uint64(1)<<sq) != 0
+	opponents := p.pieces(side.Opponent()) &^ p.Taken
+	occupied := (p.Black | p.White) &^ (uint64(1) << sq)
+	return p.captureChains(moves, side, []int{sq}, nil, 0, king, opponents, occupied)
+}
+
+func (p *Position) captureChains(moves []PositionMove, side Side, path, captured []int, kings int, king bool, opponents, occupied uint64) []PositionMove {
+	g := p.geometry()
+	sq := path[len(path)-1]
+	flying := king && p.Rules.FlyingKings
+	directions := p.moveDirections(side, king)
+	if p.Rules.MenCaptureBackwards {
+		directions = []int{0, 1, 2, 3}
+	}
+	found := false
+	for _, d := range directions {
+		ray := g.rays[sq][d]
+		i := 0
+		if flying {
+			for i < len(ray) && occupied&(uint64(1)<<ray[i]) == 0 {
+				i++
+			}
+		}
+		if i >= len(ray) {
+			continue
+		}
+		mid := ray[i]
+		midBit := uint64(1) << mid
+		if opponents&midBit == 0 {
+			continue
+		}
+		midKing := p.Kings&midBit != 0
+		if midKing && !king && p.Rules.MenCannotTakeKings {
+			continue
+		}
+		for land := i + 1; land < len(ray); land++ {
+			dest := ray[land]
+			if occupied&(uint64(1)<<dest) != 0 {
+				break
+			}
+			found = true
+			nextKings := kings
+			if midKing {
+				nextKings++
+			}
+			nextKing := king
+			if !king && p.Rules.PromoteMidCapture && p.isPromotionRow(side, dest) {
+				nextKing = true
+			}
+			nextOccupied := occupied &^ midBit
+			if p.Rules.RemoveCapturedAtEnd {
+				nextOccupied = occupied
+			}
+			moves = p.captureChains(moves, side,
+				append(path[:len(path):len(path)], dest),
+				append(captured[:len(captured):len(captured)], mid),
+				nextKings, nextKing, opponents&^midBit, nextOccupied,
+			)
+			if !flying {
+				break
+			}
+		}
+	}
+	if !found && len(captured) > 0 {
+		moves = append(moves, PositionMove{Path: path, Captures: captured, Kings: kings})
+	}
+	return moves
+}
+
+func (p *Position) isPromotionRow(side Side, sq int) bool {
+	row := p.geometry().rows[sq]
+	if side == SideWhite {
+		return row == 0
+	}
+	return row == p.Rules.BoardSize-1
+}
+
+// Apply returns the position after the move, with the piece promoted when it ends on the last row
+// and the pieces taken by the whole capture sequence removed.
+func (p Position) Apply(side Side, m PositionMove) Position {
+	from, to := uint64(1)<<m.From(), uint64(1)<<m.To()
+	king := p.Kings&from != 0
+	if !king && p.Rules.PromoteMidCapture {
+		for _, sq := range m.Path[1:] {
+			king = king || p.isPromotionRow(side, sq)
+		}
+	}
+	king = king || p.isPromotionRow(side, m.To())
+	captured := p.Taken
+	for _, sq := range m.Captures {
+		captured |= uint64(1) << sq
+	}
+	p.Taken = 0
+	p.Black &^= captured
+	p.White &^= captured
+	p.Kings &^= captured | from
+	if side == SideWhite {
+		p.White = p.White&^from | to
+	} else {
+		p.Black = p.Black&^from | to
+	}
+	if king {
+		p.Kings |= to
+	}
+	return p
+}
+
+// Perft counts the positions reached after depth plies, to measure the move generator throughput
+// and check it against the published counts of the english and international variants.
+func (p Position) Perft(side Side, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	moves := p.LegalMoves(side)
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		next := p.Apply(side, m)
+		nodes += next.Perft(side.Opponent(), depth-1)
+	}
+	return nodes
+}
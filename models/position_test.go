@@ -0,0 +1,115 @@
+package models
+
+import "testing"
+
+// Published perft counts from the initial position, the black and white sides mirror each other so
+// the counts don't depend on which side moves first.
+func TestPerft(t *testing.T) {
+	tests := []struct {
+		variant string
+		nodes   []int // Nodes at depth 1, 2, ...
+	}{
+		{variant: "english", nodes: []int{7, 49, 302, 1469, 7361, 36768, 179740, 845931}},
+		{variant: "international", nodes: []int{9, 81, 658, 4265, 27117, 167140}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.variant, func(t *testing.T) {
+			board := NewBoard(testBlackID, testWhiteID, tt.variant)
+			pos := board.Position()
+			for i, want := range tt.nodes {
+				depth := i + 1
+				if depth > 6 && testing.Short() {
+					break
+				}
+				if got := pos.Perft(SideBlack, depth); got != want {
+					t.Errorf("Perft(%d) = %d, want %d", depth, got, want)
+				}
+			}
+		})
+	}
+}
+
+// There are no published counts for the other variants, the bitboard generator is checked against
+// Board.LegalMoves instead, from the initial position and from positions with kings.
+func TestPerftMatchesBoard(t *testing.T) {
+	fens := []string{
+		"",
+		MultipleCaptureTestFEN,
+		"B:W5,23:BK10",
+		"B:WK29,18,19,22,K7:BK4,10,11,14,K26",
+		"W:WK29,18,19,22,K7:BK4,10,11,14,K26",
+	}
+	for variant := range RuleSets {
+		for _, fen := range fens {
+			if fen != "" && RuleSets[variant].BoardSize != 8 {
+				continue
+			}
+			board, playerID := testBoard(t, fen, variant)
+			side, opponentID := SideBlack, testWhiteID
+			if playerID == testWhiteID {
+				side, opponentID = SideWhite, testBlackID
+			}
+			pos := board.Position()
+			for depth := 1; depth <= 4; depth++ {
+				want := boardPerft(t, board, playerID, opponentID, depth)
+				if got := pos.Perft(side, depth); got != want {
+					t.Errorf("%s %q: Perft(%d) = %d, Board.LegalMoves counts %d", variant, fen, depth, got, want)
+				}
+			}
+		}
+	}
+}
+
+// boardPerft is Perft played with Board.LegalMoves and the hops the gameworker applies.
+func boardPerft(t *testing.T, board *Board, playerID, opponentID string, depth int) int {
+	t.Helper()
+	moves := board.LegalMoves(playerID)
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, lm := range moves {
+		game := Game{Board: *board.Clone()}
+		for _, hop := range lm.Hops(playerID) {
+			if _, err := game.MovePiece(hop); err != nil {
+				t.Fatalf("MovePiece(%s-%s): %v", hop.From, hop.To, err)
+			}
+		}
+		nodes += boardPerft(t, &game.Board, opponentID, playerID, depth-1)
+	}
+	return nodes
+}
+
+func BenchmarkLegalMoves(b *testing.B) {
+	for _, variant := range []string{"english", "international"} {
+		board := NewBoard(testBlackID, testWhiteID, variant)
+		b.Run(variant+"/Position", func(b *testing.B) {
+			pos := board.Position()
+			for i := 0; i < b.N; i++ {
+				pos.LegalMoves(SideBlack)
+			}
+		})
+		b.Run(variant+"/Board", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				board.LegalMoves(testBlackID)
+			}
+		})
+	}
+}
+
+func BenchmarkPerft(b *testing.B) {
+	for _, bench := range []struct {
+		variant string
+		depth   int
+	}{
+		{variant: "english", depth: 6},
+		{variant: "international", depth: 5},
+	} {
+		pos := NewBoard(testBlackID, testWhiteID, bench.variant).Position()
+		b.Run(bench.variant, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				pos.Perft(SideBlack, bench.depth)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReplayReport is the result of replaying a stored game through the rules engine.
+type ReplayReport struct {
+	GameID        string       `json:"game_id"`
+	Variant       string       `json:"variant"`
+	Valid         bool         `json:"valid"`
+	MovesReplayed int          `json:"moves_replayed"`
+	Passes        int          `json:"passes"`                 // Turns lost on a timeout without a move.
+	IllegalMove   *IllegalMove `json:"illegal_move,omitempty"` // First move the rules engine rejected.
+	FinalFEN      string       `json:"final_fen"`
+	StoredFEN     string       `json:"stored_fen,omitempty"` // FEN stored with the last move, when present.
+	FENMismatch   bool         `json:"fen_mismatch"`
+	Winner        WinnerReplay `json:"winner"`
+	Errors        []string     `json:"errors,omitempty"`
+}
+
+// IllegalMove is a stored move that does not follow the rules.
+type IllegalMove struct {
+	Index  int           `json:"index"` // Position of the move in the stored moves.
+	Move   Move          `json:"move"`
+	Code   MoveErrorCode `json:"code"`
+	Reason string        `json:"reason"`
+}
+
+// WinnerReplay compares the stored winner with the one the replay arrives at. Games that end by
+// timeout, player leaving or draw agreement can't be checked from the moves, Checked is false.
+type WinnerReplay struct {
+	Checked  bool   `json:"checked"`
+	Stored   string `json:"stored"`
+	Expected string `json:"expected"`
+	Mismatch bool   `json:"mismatch"`
+}
+
+// ReplayGame re-applies the stored moves of a finished game from the position it started from.
+//
+// Stored moves reference the piece IDs of the original board, the replay matches them by square and
+// checks every hop, the turn order and the promotions. With a time control that passes the turn on a
+// timeout a move of the other player means the current one ran out of time, see replayPass. The game
+// over reason is the one saved with the game, it tells how the winner is checked.
+func ReplayGame(stored Game, reason string) ReplayReport {
+	variant := GetRuleSet(stored.Variant).Name
+	report := ReplayReport{GameID: stored.ID, Variant: variant, Valid: true}
+
+	game, err := newReplayGame(stored)
+	if err != nil {
+		report.Valid = false
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	gameOver := false
+	finalFEN := game.FEN()
+	for i, storedMove := range stored.Moves {
+		if gameOver {
+			report.illegal(i, storedMove, NewMoveError(ErrIllegalMove, "move after the end of the game"))
+			break
+		}
+		move := game.replayMove(storedMove)
+		if game.replayPass(stored, move) {
+			report.Passes++
+		}
+		if err := game.ValidateMove(move); err != nil {
+			report.illegal(i, storedMove, err)
+			break
+		}
+		result, err := game.PlayHop(move)
+		if err != nil {
+			report.illegal(i, storedMove, err)
+			break
+		}
+		if result.Kinged != storedMove.IsKinged {
+			report.illegal(i, storedMove, NewMoveError(ErrIllegalMove, "stored promotion %t, replay promotion %t", storedMove.IsKinged, result.Kinged))
+			break
+		}
+		report.MovesReplayed++
+		finalFEN = result.Move.FEN
+		if game.CheckGameOver() {
+			gameOver = true
+			game.Winner = move.PlayerID
+			continue
+		}
+		if !result.TurnContinues {
+			game.passTurn()
+		}
+	}
+
+	report.FinalFEN = finalFEN
+	if n := len(stored.Moves); n > 0 && stored.Moves[n-1].FEN != "" && report.IllegalMove == nil {
+		report.StoredFEN = stored.Moves[n-1].FEN
+		report.FENMismatch = report.StoredFEN != report.FinalFEN
+		if report.FENMismatch {
+			report.Valid = false
+		}
+	}
+	if report.IllegalMove == nil {
+		report.checkWinner(game, stored.Winner, reason, gameOver)
+	}
+	return report
+}
+
+// newReplayGame sets up the board a stored game started from, the initial board of its variant with
+// black to move unless the game has a StartFEN.
+func newReplayGame(stored Game) (*Game, error) {
+	blackID, whiteID := stored.playerIDByColor("b"), stored.playerIDByColor("w")
+	if blackID == "" || whiteID == "" {
+		return nil, fmt.Errorf("game players have no colours")
+	}
+	variant := GetRuleSet(stored.Variant).Name
+	board, currentPlayerID := NewBoard(blackID, whiteID, variant), blackID
+	if stored.StartFEN != "" {
+		fenBoard, sideToMove, err := ParseFEN(stored.StartFEN, blackID, whiteID, variant)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start position: %w", err)
+		}
+		board = fenBoard
+		if sideToMove == SideWhite {
+			currentPlayerID = whiteID
+		}
+	}
+	game := &Game{
+		ID:              stored.ID,
+		Board:           *board,
+		Variant:         variant,
+		Players:         append([]GamePlayer(nil), stored.Players...),
+		CurrentPlayerID: currentPlayerID,
+		PositionCounts:  map[string]int{},
+		StartFEN:        stored.StartFEN,
+	}
+	game.UpdatePlayerPieces()
+	return game, nil
+}
+
+// replayPass passes the turn when the stored move is made by the other player of the game and the time
+// control passes the turn on a timeout, the gameworker changes the turn without storing a move then.
+// Reports if the turn was passed.
+func (g *Game) replayPass(stored Game, move Move) bool {
+	if move.PlayerID == g.CurrentPlayerID || !stored.TimeControl.PassesTurnOnTimeout() {
+		return false
+	}
+	if _, err := g.GetGamePlayer(move.PlayerID); err != nil {
+		return false
+	}
+	g.passTurn()
+	return true
+}
+
+// replayMove matches a stored move to the replayed board, by square instead of piece ID.
+func (g *Game) replayMove(stored Move) Move {
+	move := stored
+	move.Path = nil
+	if piece := g.Board.Grid[move.From]; piece != nil {
+		move.PieceID = piece.PieceID
+	}
+	return move
+}
+
+// ReplayTurn is a turn of a stored game with the position it was played from.
+type ReplayTurn struct {
+	PlayerID string   `json:"player_id"`
+	Side     Side     `json:"-"`
+	Position Position `json:"-"`
+	FEN      string   `json:"fen"`  // Position before the turn.
+	Path     []string `json:"path"` // Squares the piece went through, every hop of the turn.
+
+	// Timing of the last hop of the turn, zero for games stored before moves were timed.
+	ReceivedAt     time.Time `json:"received_at"`
+	ClockRemaining int       `json:"clock_remaining,omitempty"`
+	ThinkTime      int64     `json:"think_time_ms,omitempty"`
+}
+
+// ReplayTurns replays a stored game and groups its hops in turns, for the post-game analysis.
+// It stops at the first illegal move with an error, use ReplayGame to check a game.
+func ReplayTurns(stored Game) ([]ReplayTurn, error) {
+	game, err := newReplayGame(stored)
+	if err != nil {
+		return nil, err
+	}
+	var turns []ReplayTurn
+	var turn *ReplayTurn
+	for i, storedMove := range stored.Moves {
+		move := game.replayMove(storedMove)
+		if turn != nil && move.PlayerID != turn.PlayerID {
+			turns = append(turns, *turn) // The player ran out of time in the middle of a capture sequence.
+			turn = nil
+		}
+		game.replayPass(stored, move)
+		if err := game.ValidateMove(move); err != nil {
+			return turns, fmt.Errorf("move %d %s-%s: %v", i, storedMove.From, storedMove.To, err)
+		}
+		if turn == nil {
+			side, _ := game.Board.SideOf(move.PlayerID)
+			turn = &ReplayTurn{
+				PlayerID: move.PlayerID,
+				Side:     side,
+				Position: game.Board.Position(),
+				FEN:      game.Board.FEN(side),
+				Path:     []string{move.From},
+			}
+		}
+		result, err := game.PlayHop(move)
+		if err != nil {
+			return turns, fmt.Errorf("move %d %s-%s: %v", i, storedMove.From, storedMove.To, err)
+		}
+		turn.Path = append(turn.Path, move.To)
+		turn.ReceivedAt, turn.ClockRemaining, turn.ThinkTime = storedMove.ReceivedAt, storedMove.ClockRemaining, storedMove.ThinkTime
+		if result.TurnContinues {
+			continue
+		}
+		turns = append(turns, *turn)
+		turn = nil
+		if game.CheckGameOver() {
+			break
+		}
+		game.passTurn()
+	}
+	return turns, nil
+}
+
+func (r *ReplayReport) illegal(index int, move Move, err error) {
+	r.Valid = false
+	code, reason := ErrIllegalMove, err.Error()
+	if moveErr, ok := err.(*MoveError); ok {
+		code, reason = moveErr.Code, moveErr.Message
+	}
+	r.IllegalMove = &IllegalMove{Index: index, Move: move, Code: code, Reason: reason}
+}
+
+// checkWinner works out the winner for the game over reasons decided on the board.
+func (r *ReplayReport) checkWinner(game *Game, storedWinner, reason string, gameOver bool) {
+	r.Winner.Stored = storedWinner
+	switch reason {
+	case "winner":
+		r.Winner.Checked = true
+		if !gameOver {
+			r.Errors = append(r.Errors, "game over reason is winner but both players have pieces left")
+			break
+		}
+		r.Winner.Expected = game.Winner
+	case "no_moves":
+		r.Winner.Checked = true
+		if gameOver || !game.IsCurrentPlayerBlocked() {
+			r.Errors = append(r.Errors, fmt.Sprintf("game over reason is no_moves but %s can move", game.CurrentPlayerID))
+			break
+		}
+		r.Winner.Expected, _ = game.GetOpponentPlayerID(game.CurrentPlayerID)
+	default:
+		if gameOver {
+			r.Valid = false
+			r.Errors = append(r.Errors, fmt.Sprintf("game over reason is %s but the last move ended the game", reason))
+		}
+		return
+	}
+	r.Winner.Mismatch = r.Winner.Expected != r.Winner.Stored
+	if r.Winner.Mismatch || len(r.Errors) > 0 {
+		r.Valid = false
+	}
+}
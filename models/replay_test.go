@@ -0,0 +1,122 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// passGame is a game where black ran out of time on its second turn and lost the turn.
+func passGame(t *testing.T, timeControl TimeControl) *Game {
+	t.Helper()
+	game := testGame(t, "", "classic")
+	game.TimeControl = timeControl
+	playTurn(t, game, "C3", "D4")
+	playTurn(t, game, "F4", "E3")
+	game.NextPlayer() // Black lost the turn on a timeout.
+	playTurn(t, game, "G3", "F4")
+	playTurn(t, game, "C5", "D6")
+	return game
+}
+
+func TestReplayGame(t *testing.T) {
+	game := testGame(t, "", "english")
+	playTurn(t, game, "C3", "D4")
+	playTurn(t, game, "F6", "E5")
+	playTurn(t, game, "D4", "F6")
+	game.FinishGameAsDraw("agreement")
+
+	report := ReplayGame(*game, "draw")
+	if !report.Valid || report.MovesReplayed != 3 || report.Passes != 0 {
+		t.Errorf("ReplayGame() = valid %v, %d moves, %d passes, want valid, 3 moves and no passes: %+v", report.Valid, report.MovesReplayed, report.Passes, report)
+	}
+	if report.FinalFEN != game.Moves[2].FEN || report.FENMismatch {
+		t.Errorf("FinalFEN = %s, want %s", report.FinalFEN, game.Moves[2].FEN)
+	}
+}
+
+func TestReplayGameIllegalMove(t *testing.T) {
+	game := testGame(t, "", "classic")
+	playTurn(t, game, "C3", "D4")
+	game.Moves = append(game.Moves, Move{PlayerID: testWhiteID, From: "F2", To: "D4"})
+
+	report := ReplayGame(*game, "player_left")
+	if report.Valid || report.IllegalMove == nil || report.IllegalMove.Index != 1 {
+		t.Fatalf("ReplayGame() = %+v, want move 1 reported as illegal", report)
+	}
+}
+
+func TestReplayGamePassedTurns(t *testing.T) {
+	game := passGame(t, TimeControl{Name: TimerReset, Mode: TimerReset, Base: 30})
+	report := ReplayGame(*game, "player_left")
+	if !report.Valid || report.Passes != 1 || report.MovesReplayed != 4 {
+		t.Errorf("ReplayGame() = valid %v, %d moves, %d passes, want valid, 4 moves and 1 pass: %+v", report.Valid, report.MovesReplayed, report.Passes, report)
+	}
+
+	// Cumulative clocks end the game on a timeout, a move out of turn is illegal.
+	game = passGame(t, TimeControl{Name: TimerFischer, Mode: TimerFischer, Base: 180, Increment: 2})
+	report = ReplayGame(*game, "player_left")
+	if report.Valid || report.IllegalMove == nil || report.IllegalMove.Code != ErrNotYourTurn {
+		t.Errorf("ReplayGame() = %+v, want a NOT_YOUR_TURN illegal move", report)
+	}
+}
+
+func TestReplayTurnsPassedTurns(t *testing.T) {
+	game := passGame(t, TimeControl{Name: TimerReset, Mode: TimerReset, Base: 30})
+	turns, err := ReplayTurns(*game)
+	if err != nil {
+		t.Fatalf("ReplayTurns: %v", err)
+	}
+	var got []string
+	for _, turn := range turns {
+		got = append(got, turn.PlayerID+":"+turn.Path[0]+"-"+turn.Path[len(turn.Path)-1])
+	}
+	want := []string{"black:C3-D4", "white:F4-E3", "white:G3-F4", "black:C5-D6"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ReplayTurns() = %v, want %v", got, want)
+	}
+	if turns[2].Side != SideWhite {
+		t.Errorf("turn 2 side = %s, want W", turns[2].Side)
+	}
+}
+
+func TestReplayTurnsTiming(t *testing.T) {
+	game := passGame(t, TimeControl{Name: TimerReset, Mode: TimerReset, Base: 30})
+	receivedAt := time.Date(2025, 3, 1, 12, 0, 5, 0, time.UTC)
+	for i := range game.Moves {
+		game.Moves[i].ReceivedAt = receivedAt.Add(time.Duration(i) * time.Second)
+		game.Moves[i].ClockRemaining = 100 - i
+		game.Moves[i].ThinkTime = int64(1000 + i)
+	}
+
+	turns, err := ReplayTurns(*game)
+	if err != nil {
+		t.Fatalf("ReplayTurns: %v", err)
+	}
+	if len(turns) != len(game.Moves) {
+		t.Fatalf("ReplayTurns() = %d turns, want %d", len(turns), len(game.Moves))
+	}
+	for i, turn := range turns {
+		move := game.Moves[i]
+		if !turn.ReceivedAt.Equal(move.ReceivedAt) || turn.ClockRemaining != move.ClockRemaining || turn.ThinkTime != move.ThinkTime {
+			t.Errorf("turn %d timing = %v, %ds, %dms, want %v, %ds, %dms", i, turn.ReceivedAt, turn.ClockRemaining, turn.ThinkTime, move.ReceivedAt, move.ClockRemaining, move.ThinkTime)
+		}
+	}
+}
+
+func TestReplayGameStartFEN(t *testing.T) {
+	game := testGame(t, MultipleCaptureTestFEN, "classic")
+	game.StartFEN = MultipleCaptureTestFEN
+	playTurn(t, game, "C5", "E7", "G5")
+	game.FinishGame(testBlackID)
+
+	report := ReplayGame(*game, "winner")
+	if !report.Valid || !report.Winner.Checked || report.Winner.Expected != testBlackID {
+		t.Errorf("ReplayGame() = %+v, want a valid game won by black", report)
+	}
+
+	game.StartFEN = ""
+	if report := ReplayGame(*game, "winner"); report.Valid {
+		t.Error("ReplayGame() from the initial position accepted the moves of the test position")
+	}
+}
@@ -16,6 +16,7 @@ type Room struct {
 	CurrentPlayerID string     `json:"current_player_id"`
 	IsRoomOpen      bool       `json:"is_room_open"`
 	OperatorIdentifier OperatorIdentifier `json:"operator_identifier"`
+	IsPractice         bool               `json:"is_practice,omitempty"` // Practice rooms are free, the players don't bet.
 }
 
 func (r *Room) GetOpponentPlayerID(playerID string) (string, error) {
@@ -0,0 +1,93 @@
+package models
+
+// RuleSet describes a checkers variant, the move generator reads it from the board variant.
+type RuleSet struct {
+	Name                string `json:"name"`
+	BoardSize           int    `json:"board_size"`             // Squares per side, 8 or 10.
+	MenCaptureBackwards bool   `json:"men_capture_backwards"`  // Men can capture in all four diagonals.
+	FlyingKings         bool   `json:"flying_kings"`           // Kings move and capture along the whole diagonal.
+	MandatoryMaxCapture bool   `json:"mandatory_max_capture"`  // The capture sequence that takes the most pieces must be played.
+	PromoteMidCapture   bool   `json:"promote_mid_capture"`    // A man reaching the last row mid capture is promoted and keeps capturing as a king.
+	KingCapturePriority bool   `json:"king_capture_priority"`  // Between sequences of the same length, a king must capture and must take the most kings.
+	MenCannotTakeKings  bool   `json:"men_cannot_take_kings"`  // Kings can only be captured by kings.
+	RemoveCapturedAtEnd bool   `json:"remove_captured_at_end"` // Captured pieces stay on the board until the sequence ends, they block and can't be jumped twice.
+}
+
+const DefaultVariant = "classic"
+
+// MaxBoardSize is the largest board any variant uses, positions go up to J10.
+const MaxBoardSize = 10
+
+// RuleSets holds the supported variants, keyed by the variant name stored on operators and games.
+var RuleSets = map[string]RuleSet{
+	// The rules the game shipped with, men only capture forward and kings fly.
+	"classic": {
+		Name:        "classic",
+		BoardSize:   8,
+		FlyingKings: true,
+	},
+	"english": {
+		Name:      "english",
+		BoardSize: 8,
+	},
+	"brazilian": {
+		Name:                "brazilian",
+		BoardSize:           8,
+		MenCaptureBackwards: true,
+		FlyingKings:         true,
+		MandatoryMaxCapture: true,
+		RemoveCapturedAtEnd: true,
+	},
+	"international": {
+		Name:                "international",
+		BoardSize:           10,
+		MenCaptureBackwards: true,
+		FlyingKings:         true,
+		MandatoryMaxCapture: true,
+		RemoveCapturedAtEnd: true,
+	},
+	"russian": {
+		Name:                "russian",
+		BoardSize:           8,
+		MenCaptureBackwards: true,
+		FlyingKings:         true,
+		PromoteMidCapture:   true,
+		RemoveCapturedAtEnd: true,
+	},
+	"italian": {
+		Name:                "italian",
+		BoardSize:           8,
+		MandatoryMaxCapture: true,
+		KingCapturePriority: true,
+		MenCannotTakeKings:  true,
+	},
+}
+
+// GetRuleSet returns the rules for the variant, unknown or empty variants use the DefaultVariant.
+func GetRuleSet(variant string) RuleSet {
+	if rules, ok := RuleSets[variant]; ok {
+		return rules
+	}
+	return RuleSets[DefaultVariant]
+}
+
+// IsValidVariant reports if the variant is one of the supported RuleSets.
+func IsValidVariant(variant string) bool {
+	_, ok := RuleSets[variant]
+	return ok
+}
+
+// PieceRows is the number of rows each player fills at the start of the game.
+func (rs RuleSet) PieceRows() int {
+	return rs.BoardSize/2 - 1
+}
+
+// PiecesPerPlayer is the number of pieces each player starts with.
+func (rs RuleSet) PiecesPerPlayer() int {
+	return rs.PieceRows() * rs.BoardSize / 2
+}
+
+// LastRow is the row letter of the bottom row of the board.
+func (rs RuleSet) LastRow() rune {
+	return 'A' + rune(rs.BoardSize-1)
+}
@@ -9,6 +9,8 @@ type Operator struct {
 	GameBaseUrl           string  `json:"game_base_url"`
 	OperatorWalletBaseUrl string  `json:"operator_wallet_base_url"`
 	WinFactor             float64 `json:"win_factor"`
+	Variant               string  `json:"variant"`
+	TimeControl           string  `json:"time_control"` // Time control preset of the operator games, empty uses the bet or default clock.
 }
 
 type WalletResponse struct {
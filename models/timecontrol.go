@@ -0,0 +1,104 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Lavizord/checkers-server/config"
+)
+
+// Clock modes of the time controls.
+const (
+	TimerReset      = "reset"      // Every turn has Base seconds, the turn is passed when they run out.
+	TimerCumulative = "cumulative" // Each player has Base seconds for the whole game.
+	TimerFischer    = "fischer"    // Cumulative, Increment seconds are added to the clock after every turn.
+	TimerBronstein  = "bronstein"  // Cumulative, the time used in a turn is given back, up to Delay seconds.
+)
+
+// TimeControl is the clock a game is played with.
+type TimeControl struct {
+	Name      string `json:"name"`
+	Mode      string `json:"mode"`
+	Base      int    `json:"base"`                // Seconds each player starts with, seconds per turn in reset mode.
+	Increment int    `json:"increment,omitempty"` // Fischer increment in seconds.
+	Delay     int    `json:"delay,omitempty"`     // Bronstein delay in seconds.
+}
+
+// TimeControls holds the time control presets, keyed by the name stored on operators and in the
+// gameworker bet_time_controls.
+var TimeControls = map[string]TimeControl{
+	"bullet":      {Name: "bullet", Mode: TimerFischer, Base: 60, Increment: 1},
+	"blitz":       {Name: "blitz", Mode: TimerFischer, Base: 180, Increment: 2},
+	"rapid":       {Name: "rapid", Mode: TimerFischer, Base: 600, Increment: 5},
+	"blitz_delay": {Name: "blitz_delay", Mode: TimerBronstein, Base: 180, Delay: 3},
+	"rapid_delay": {Name: "rapid_delay", Mode: TimerBronstein, Base: 600, Delay: 5},
+}
+
+// DefaultTimeControl is the clock set with the gameworker timer and timer_setting, for games without a preset.
+func DefaultTimeControl() TimeControl {
+	cfg := config.Cfg.Services["gameworker"]
+	if cfg.TimerSetting == TimerCumulative {
+		return TimeControl{Name: TimerCumulative, Mode: TimerCumulative, Base: cfg.Timer*cfg.PiecesInMatch + 1}
+	}
+	return TimeControl{Name: TimerReset, Mode: TimerReset, Base: cfg.Timer}
+}
+
+// GetTimeControl returns the preset, unknown or empty names use the DefaultTimeControl.
+func GetTimeControl(name string) TimeControl {
+	if tc, ok := TimeControls[name]; ok {
+		return tc
+	}
+	return DefaultTimeControl()
+}
+
+// IsValidTimeControl reports if the name is one of the TimeControls presets.
+func IsValidTimeControl(name string) bool {
+	_, ok := TimeControls[name]
+	return ok
+}
+
+// SelectTimeControl picks the clock of a game, the operator preset first, then the preset of the bet
+// in the gameworker bet_time_controls, e.g. {"0.5": "bullet"}, and then the DefaultTimeControl.
+func SelectTimeControl(operatorPreset string, bet float64) TimeControl {
+	if IsValidTimeControl(operatorPreset) {
+		return TimeControls[operatorPreset]
+	}
+	betPresets := config.Cfg.Services["gameworker"].BetTimeControls
+	return GetTimeControl(betPresets[strconv.FormatFloat(bet, 'f', -1, 64)])
+}
+
+// Remaining returns the seconds left on a clock that had clock seconds when the turn started, elapsed
+// into the turn. It is 0 or less once the time has run out.
+func (tc TimeControl) Remaining(clock int, elapsed time.Duration) int {
+	if tc.Mode == TimerReset {
+		clock = tc.Base
+	}
+	return clock - usedSeconds(elapsed)
+}
+
+// AfterTurn returns the clock of a player that ends a turn that took elapsed, with the increment or the
+// delay of the mode applied.
+func (tc TimeControl) AfterTurn(clock int, elapsed time.Duration) int {
+	used := usedSeconds(elapsed)
+	switch tc.Mode {
+	case TimerReset:
+		return tc.Base
+	case TimerFischer:
+		return clock - used + tc.Increment
+	case TimerBronstein:
+		return clock - used + min(used, tc.Delay)
+	default:
+		return clock - used
+	}
+}
+
+// PassesTurnOnTimeout reports if running out of time passes the turn, instead of losing the game.
+func (tc TimeControl) PassesTurnOnTimeout() bool {
+	return tc.Mode == TimerReset
+}
+
+// usedSeconds is the time used in a turn as every clock counts it, the whole seconds that passed. A player
+// that moves before the clock reaches 0 keeps at least a second.
+func usedSeconds(elapsed time.Duration) int {
+	return int(elapsed.Seconds())
+}
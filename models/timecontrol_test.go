@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Lavizord/checkers-server/config"
+)
+
+// setGameworkerConfig replaces the gameworker settings for the test.
+func setGameworkerConfig(t *testing.T, settings string) {
+	t.Helper()
+	saved := config.Cfg
+	t.Cleanup(func() { config.Cfg = saved })
+	config.Cfg = config.Config{}
+	if err := json.Unmarshal([]byte(`{"services": {"gameworker": `+settings+`}}`), &config.Cfg); err != nil {
+		t.Fatalf("gameworker settings %s: %v", settings, err)
+	}
+}
+
+func TestTimeControlAfterTurn(t *testing.T) {
+	tests := []struct {
+		name    string
+		tc      TimeControl
+		clock   int
+		elapsed time.Duration
+		want    int
+	}{
+		{name: "reset gives the whole turn back", tc: TimeControl{Mode: TimerReset, Base: 15}, clock: 3, elapsed: 12 * time.Second, want: 15},
+		{name: "cumulative", tc: TimeControl{Mode: TimerCumulative, Base: 180}, clock: 100, elapsed: 7 * time.Second, want: 93},
+		{name: "fischer adds the increment", tc: TimeControls["blitz"], clock: 100, elapsed: 7 * time.Second, want: 95},
+		{name: "fischer on a quick turn", tc: TimeControls["blitz"], clock: 100, elapsed: 500 * time.Millisecond, want: 102},
+		{name: "bronstein gives back up to the delay", tc: TimeControls["blitz_delay"], clock: 100, elapsed: 7 * time.Second, want: 96},
+		{name: "bronstein gives back a turn shorter than the delay", tc: TimeControls["blitz_delay"], clock: 100, elapsed: 2 * time.Second, want: 100},
+		{name: "used time is counted in whole seconds", tc: TimeControl{Mode: TimerCumulative}, clock: 100, elapsed: 2600 * time.Millisecond, want: 98},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tc.AfterTurn(tt.clock, tt.elapsed); got != tt.want {
+				t.Errorf("AfterTurn(%d, %v) = %d, want %d", tt.clock, tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeControlRemaining(t *testing.T) {
+	reset := TimeControl{Mode: TimerReset, Base: 15}
+	if got := reset.Remaining(3, 4*time.Second); got != 11 {
+		t.Errorf("reset Remaining(3, 4s) = %d, want 11, the turn starts with Base", got)
+	}
+	blitz := TimeControls["blitz"]
+	if got := blitz.Remaining(30, 4900*time.Millisecond); got != 26 {
+		t.Errorf("blitz Remaining(30, 4.9s) = %d, want 26", got)
+	}
+	if got := blitz.Remaining(3, 5*time.Second); got >= 0 {
+		t.Errorf("blitz Remaining(3, 5s) = %d, want below 0 once the time ran out", got)
+	}
+}
+
+// A move just before the clock reaches 0 leaves the clock with what Remaining showed.
+func TestTimeControlRemainingMatchesAfterTurn(t *testing.T) {
+	cumulative := TimeControl{Mode: TimerCumulative, Base: 180}
+	for _, elapsed := range []time.Duration{0, 400 * time.Millisecond, 2600 * time.Millisecond, 9999 * time.Millisecond} {
+		if remaining, after := cumulative.Remaining(10, elapsed), cumulative.AfterTurn(10, elapsed); remaining != after {
+			t.Errorf("after %v Remaining = %d but AfterTurn = %d", elapsed, remaining, after)
+		}
+	}
+	if got := cumulative.AfterTurn(10, 9999*time.Millisecond); got < 1 {
+		t.Errorf("AfterTurn(10, 9.999s) = %d, want a second left on a move before the time ran out", got)
+	}
+}
+
+func TestPassesTurnOnTimeout(t *testing.T) {
+	for _, mode := range []string{TimerReset, TimerCumulative, TimerFischer, TimerBronstein} {
+		tc := TimeControl{Mode: mode}
+		if got, want := tc.PassesTurnOnTimeout(), mode == TimerReset; got != want {
+			t.Errorf("%s PassesTurnOnTimeout() = %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestSelectTimeControl(t *testing.T) {
+	setGameworkerConfig(t, `{"timer": 15, "timer_setting": "reset", "pieces_in_match": 12, "bet_time_controls": {"0.5": "bullet", "2": "nope"}}`)
+	tests := []struct {
+		name     string
+		operator string
+		bet      float64
+		want     string
+	}{
+		{name: "operator preset first", operator: "rapid", bet: 0.5, want: "rapid"},
+		{name: "bet preset", bet: 0.5, want: "bullet"},
+		{name: "unknown operator preset", operator: "nope", bet: 0.5, want: "bullet"},
+		{name: "bet without a preset", bet: 1, want: TimerReset},
+		{name: "unknown bet preset", bet: 2, want: TimerReset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectTimeControl(tt.operator, tt.bet); got.Name != tt.want {
+				t.Errorf("SelectTimeControl(%q, %v) = %s, want %s", tt.operator, tt.bet, got.Name, tt.want)
+			}
+		})
+	}
+	if got := SelectTimeControl("", 1); got.Base != 15 {
+		t.Errorf("reset default Base = %d, want the gameworker timer 15", got.Base)
+	}
+}
+
+func TestDefaultTimeControlCumulative(t *testing.T) {
+	setGameworkerConfig(t, `{"timer": 15, "timer_setting": "cumulative", "pieces_in_match": 12}`)
+	want := TimeControl{Name: TimerCumulative, Mode: TimerCumulative, Base: 181}
+	if got := DefaultTimeControl(); got != want {
+		t.Errorf("DefaultTimeControl() = %+v, want %+v", got, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/Lavizord/checkers-server/models"
 
@@ -115,11 +116,29 @@ func (pc *PostgresCli) SaveGame(game models.Game, reason string) error {
 		return fmt.Errorf("error marshalling players: %w", err)
 	}
 
+	timeControlJSON, err := json.Marshal(game.TimeControl)
+	if err != nil {
+		return fmt.Errorf("error marshalling time control: %w", err)
+	}
+
+	// A draw has no winner, Winner is stored as NULL, and only draws have a DrawRule. StartFEN is NULL
+	// for games played from the initial position.
+	var winner, drawRule, startFEN sql.NullString
+	if game.Winner != "" {
+		winner = sql.NullString{String: game.Winner, Valid: true}
+	}
+	if game.DrawRule != "" {
+		drawRule = sql.NullString{String: game.DrawRule, Valid: true}
+	}
+	if game.StartFEN != "" {
+		startFEN = sql.NullString{String: game.StartFEN, Valid: true}
+	}
+
 	// SQL query to insert the game data
 	query := `
 		INSERT INTO games (
-			ID, OperatorName, OperatorGameName, GameName, StartDate, EndDate, Moves, BetAmount, Winner, GamePlayers, WinFactor, NumMoves, GameOverReason
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			ID, OperatorName, OperatorGameName, GameName, StartDate, EndDate, Moves, BetAmount, Winner, GamePlayers, WinFactor, NumMoves, GameOverReason, Variant, IsPractice, TimeControl, DrawRule, StartFEN
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id
 	`
 	var gameID string
@@ -133,11 +152,16 @@ func (pc *PostgresCli) SaveGame(game models.Game, reason string) error {
 		game.EndTime,
 		movesJSON,
 		game.BetValue,
-		game.Winner,
+		winner,
 		playersJSON,
 		game.OperatorIdentifier.WinFactor,
 		len(game.Moves),
 		reason,
+		game.Variant,
+		game.IsPractice,
+		timeControlJSON,
+		drawRule,
+		startFEN,
 	).Scan(&gameID)
 
 	if err != nil {
@@ -148,10 +172,93 @@ func (pc *PostgresCli) SaveGame(game models.Game, reason string) error {
 	return nil
 }
 
+// FetchGame fetches a finished game by ID, with the game over reason it was saved with.
+// Only the moves and the StartFEN are stored, the returned game has an empty board.
+func (pc *PostgresCli) FetchGame(gameID string) (*models.Game, string, error) {
+	query := `
+		SELECT ID, OperatorName, OperatorGameName, GameName, StartDate, EndDate, Moves, BetAmount,
+			COALESCE(Winner::text, ''), GamePlayers, WinFactor, COALESCE(GameOverReason, ''), COALESCE(Variant, ''),
+			COALESCE(IsPractice, FALSE), COALESCE(TimeControl, '{}'), COALESCE(DrawRule, ''),
+			COALESCE(StartFEN, '')
+		FROM games
+		WHERE ID = $1
+	`
+	row := pc.DB.QueryRow(query, gameID)
+
+	var game models.Game
+	var movesJSON, playersJSON, timeControlJSON []byte
+	var reason string
+	err := row.Scan(
+		&game.ID,
+		&game.OperatorIdentifier.OperatorName,
+		&game.OperatorIdentifier.OperatorGameName,
+		&game.OperatorIdentifier.GameName,
+		&game.StartTime,
+		&game.EndTime,
+		&movesJSON,
+		&game.BetValue,
+		&game.Winner,
+		&playersJSON,
+		&game.OperatorIdentifier.WinFactor,
+		&reason,
+		&game.Variant,
+		&game.IsPractice,
+		&timeControlJSON,
+		&game.DrawRule,
+		&game.StartFEN,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", fmt.Errorf("game not found with ID=%s", gameID)
+		}
+		return nil, "", fmt.Errorf("error fetching game: %w", err)
+	}
+	if err := json.Unmarshal(movesJSON, &game.Moves); err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling moves: %w", err)
+	}
+	if err := json.Unmarshal(playersJSON, &game.Players); err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling players: %w", err)
+	}
+	if err := json.Unmarshal(timeControlJSON, &game.TimeControl); err != nil {
+		return nil, "", fmt.Errorf("error unmarshalling time control: %w", err)
+	}
+	game.TimerSetting = game.TimeControl.Mode
+	game.Variant = models.GetRuleSet(game.Variant).Name
+	game.OperatorIdentifier.Variant = game.Variant
+	game.Board = models.Board{Grid: map[string]*models.Piece{}, Variant: game.Variant}
+	return &game, reason, nil
+}
+
+// FetchGameIDs returns the IDs of the games that ended between from and to, oldest first.
+func (pc *PostgresCli) FetchGameIDs(from, to time.Time) ([]string, error) {
+	query := `
+		SELECT ID
+		FROM games
+		WHERE EndDate >= $1 AND EndDate < $2
+		ORDER BY EndDate
+	`
+	rows, err := pc.DB.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching game ids: %w", err)
+	}
+	defer rows.Close()
+
+	var gameIDs []string
+	for rows.Next() {
+		var gameID string
+		if err := rows.Scan(&gameID); err != nil {
+			return nil, fmt.Errorf("error scanning game id: %w", err)
+		}
+		gameIDs = append(gameIDs, gameID)
+	}
+	return gameIDs, rows.Err()
+}
+
 // FetchOperator fetches an operator from the database using OperatorName and OperatorGameName
 func (pc *PostgresCli) FetchOperator(operatorName, operatorGameName string) (*models.Operator, error) {
 	query := `
-		SELECT ID, OperatorName, OperatorGameName, GameName, Active, GameBaseUrl, OperatorWalletBaseUrl, WinFactor
+		SELECT ID, OperatorName, OperatorGameName, GameName, Active, GameBaseUrl, OperatorWalletBaseUrl, WinFactor, COALESCE(Variant, ''),
+			COALESCE(TimeControl, '')
 		FROM operators
 		WHERE OperatorName = $1 AND OperatorGameName = $2
 	`
@@ -167,6 +274,8 @@ func (pc *PostgresCli) FetchOperator(operatorName, operatorGameName string) (*mo
 		&operator.GameBaseUrl,
 		&operator.OperatorWalletBaseUrl,
 		&operator.WinFactor,
+		&operator.Variant,
+		&operator.TimeControl,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -64,7 +64,7 @@ func handleRemovePlayer(player *models.Player) {
 	}
 	if player.GameID != "" || player.Status == models.StatusInGame {
 		//log.Printf("[PStatus Worker-%d] - Removed player is in a Game, sending notification to Game worker!: %v\n", pid, player)
-		redisClient.RPush("disconnect_game", player)
+		redisClient.SendGameCommand(models.GameCommandDisconnect, player.GameID, player)
 	}
 
 	err := redisClient.RemovePlayer(string(player.ID))
@@ -90,7 +90,7 @@ func handleDisconnectPlayer(player *models.Player) {
 	// If its in a game we push a disconnected game command.
 	if player.GameID != "" || player.Status == models.StatusInGame {
 		//log.Printf("[PStatus Worker-%d] - Removed player is in a Game, sending notification to Game worker!: %v\n", pid, player)
-		redisClient.RPush("disconnect_game", player)
+		redisClient.SendGameCommand(models.GameCommandDisconnect, player.GameID, player)
 	} else {
 		// If the player is not in game we will remove it.
 		// If it is in game we will need to keep it in the redis memory.
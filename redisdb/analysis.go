@@ -0,0 +1,39 @@
+package redisdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The analyses of finished games don't change, they are cached so each game is only analysed once.
+func analysisKey(gameID string, turn int) string {
+	if turn < 0 {
+		return fmt.Sprintf("analysis:%s", gameID)
+	}
+	return fmt.Sprintf("analysis:%s:%d", gameID, turn)
+}
+
+// GetAnalysis returns the cached analysis of the game, turn below 0, or of a single turn, nil when it is
+// not cached.
+func (r *RedisClient) GetAnalysis(gameID string, turn int) ([]byte, error) {
+	data, err := r.Client.Get(context.Background(), analysisKey(gameID, turn)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to get analysis of game %s: %v", gameID, err)
+	}
+	return data, nil
+}
+
+// SaveAnalysis caches the analysis of the game, turn below 0, or of a single turn, for ttl.
+func (r *RedisClient) SaveAnalysis(gameID string, turn int, data []byte, ttl time.Duration) error {
+	if err := r.Client.Set(context.Background(), analysisKey(gameID, turn), data, ttl).Err(); err != nil {
+		return fmt.Errorf("[RedisClient] - failed to save analysis of game %s: %v", gameID, err)
+	}
+	return nil
+}
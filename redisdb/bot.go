@@ -0,0 +1,78 @@
+package redisdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+// Bots are kept in a hash until their game is over, so they outlive the botworker that runs them.
+// Each bot is run by the botworker that holds its lease, see ClaimBot.
+const botsKey = "bots"
+
+func botOwnerKey(botID string) string {
+	return fmt.Sprintf("bots:%s:owner", botID)
+}
+
+// SaveBot stores the bot with its settings.
+func (r *RedisClient) SaveBot(bot models.Player) error {
+	data, err := json.Marshal(bot)
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to serialize bot: %v", err)
+	}
+	if err := r.Client.HSet(context.Background(), botsKey, bot.ID, data).Err(); err != nil {
+		return fmt.Errorf("[RedisClient] - failed to save bot %s: %w", bot.ID, err)
+	}
+	return nil
+}
+
+// GetBots returns every stored bot.
+func (r *RedisClient) GetBots() ([]models.Player, error) {
+	values, err := r.Client.HGetAll(context.Background(), botsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to get bots: %w", err)
+	}
+	bots := make([]models.Player, 0, len(values))
+	for id, data := range values {
+		var bot models.Player
+		if err := json.Unmarshal([]byte(data), &bot); err != nil {
+			return nil, fmt.Errorf("[RedisClient] - failed to deserialize bot %s: %v", id, err)
+		}
+		bots = append(bots, bot)
+	}
+	return bots, nil
+}
+
+// RemoveBot removes the bot and its lease once its game is over.
+func (r *RedisClient) RemoveBot(botID string) error {
+	ctx := context.Background()
+	if err := r.Client.HDel(ctx, botsKey, botID).Err(); err != nil {
+		return fmt.Errorf("[RedisClient] - failed to remove bot %s: %w", botID, err)
+	}
+	if err := r.Client.Del(ctx, botOwnerKey(botID)).Err(); err != nil {
+		return fmt.Errorf("[RedisClient] - failed to remove the lease of bot %s: %w", botID, err)
+	}
+	return nil
+}
+
+// ClaimBot takes or extends the lease of a bot for the owner, it returns false while another botworker
+// holds it. The lease expires when the owner stops extending it, and the bot can be adopted.
+func (r *RedisClient) ClaimBot(botID, owner string, lease time.Duration) (bool, error) {
+	claimed, err := claimLeaseScript.Run(context.Background(), r.Client, []string{botOwnerKey(botID)}, owner, lease.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("[RedisClient] - failed to claim bot %s: %v", botID, err)
+	}
+	return claimed == 1, nil
+}
+
+// ReleaseBot gives up the lease of a bot, so another botworker can adopt it without waiting for it to expire.
+func (r *RedisClient) ReleaseBot(botID, owner string) error {
+	err := releaseLeaseScript.Run(context.Background(), r.Client, []string{botOwnerKey(botID)}, owner).Err()
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to release bot %s: %v", botID, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package redisdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// The game clocks are kept in a sorted set of game IDs scored with the time the current player runs
+// out of time, in unix milliseconds, so any gameworker can fire the timeouts.
+const gameClocksKey = "game_clocks"
+
+// SetGameDeadline sets when the current player of the game runs out of time, replacing the last deadline.
+func (r *RedisClient) SetGameDeadline(gameID string, deadline time.Time) error {
+	err := r.Client.ZAdd(context.Background(), gameClocksKey, redis.Z{Score: float64(deadline.UnixMilli()), Member: gameID}).Err()
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to set game deadline: %v", err)
+	}
+	return nil
+}
+
+// RemoveGameDeadline stops the clock of a game that is over.
+func (r *RedisClient) RemoveGameDeadline(gameID string) error {
+	err := r.Client.ZRem(context.Background(), gameClocksKey, gameID).Err()
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to remove game deadline: %v", err)
+	}
+	return nil
+}
+
+// fireDeadlineScript sends the timeout command of a game to its mailbox and only then removes the
+// deadline, in one step, when the deadline is still due. A deadline moved or removed since it was
+// read is left alone, and a failed push keeps the deadline for the next look.
+var fireDeadlineScript = redis.NewScript(`
+local deadline = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not deadline or tonumber(deadline) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[4], '*', 'payload', ARGV[3])
+redis.call('ZREM', KEYS[1], ARGV[1])
+return 1
+`)
+
+// FireExpiredGames sends a timeout command to the mailbox of each game whose deadline passed at now
+// and returns their IDs. When several gameworkers look at the same deadline only one fires it.
+func (r *RedisClient) FireExpiredGames(now time.Time) ([]string, error) {
+	ctx := context.Background()
+	nowMilli := now.UnixMilli()
+	gameIDs, err := r.Client.ZRangeByScore(ctx, gameClocksKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(nowMilli, 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to get expired games: %v", err)
+	}
+	var fired []string
+	for _, gameID := range gameIDs {
+		command, _ := models.NewGameCommand(models.GameCommandTimeout, gameID, nil)
+		data, err := json.Marshal(command)
+		if err != nil {
+			return fired, fmt.Errorf("[RedisClient] - failed to serialize game command: %v", err)
+		}
+		keys := []string{gameClocksKey, streamKey(GameCommandsQueue(GameCommandShard(gameID)))}
+		sent, err := fireDeadlineScript.Run(ctx, r.Client, keys, gameID, nowMilli, string(data), streamMaxLen).Int()
+		if err != nil {
+			return fired, fmt.Errorf("[RedisClient] - failed to fire game deadline: %v", err)
+		}
+		if sent == 1 {
+			fired = append(fired, gameID)
+		}
+	}
+	return fired, nil
+}
+
+// GetClockedGames returns the IDs of every game with a running clock.
+func (r *RedisClient) GetClockedGames() ([]string, error) {
+	gameIDs, err := r.Client.ZRange(context.Background(), gameClocksKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to get clocked games: %v", err)
+	}
+	return gameIDs, nil
+}
+
+// ClaimClockTick lets a single gameworker send the game timers of each second, it returns true for
+// the first one that claims the tick.
+func (r *RedisClient) ClaimClockTick(tick time.Time) (bool, error) {
+	key := fmt.Sprintf("game_clocks:tick:%d", tick.Unix())
+	claimed, err := r.Client.SetNX(context.Background(), key, 1, 5*time.Second).Result()
+	if err != nil {
+		return false, fmt.Errorf("[RedisClient] - failed to claim clock tick: %v", err)
+	}
+	return claimed, nil
+}
@@ -0,0 +1,67 @@
+package redisdb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+func TestFireExpiredGames(t *testing.T) {
+	client, _ := testRedisClient(t)
+	ctx := context.Background()
+	now := time.Now()
+	client.SetGameDeadline("expired", now.Add(-time.Second))
+	client.SetGameDeadline("due", now)
+	client.SetGameDeadline("running", now.Add(time.Second))
+
+	fired, err := client.FireExpiredGames(now)
+	if err != nil {
+		t.Fatalf("FireExpiredGames: %v", err)
+	}
+	if len(fired) != 2 || fired[0] != "expired" || fired[1] != "due" {
+		t.Errorf("fired %v, want [expired due]", fired)
+	}
+	for _, gameID := range []string{"expired", "due"} {
+		messages, err := client.Client.XRange(ctx, streamKey(GameCommandsQueue(GameCommandShard(gameID))), "-", "+").Result()
+		if err != nil {
+			t.Fatalf("XRange: %v", err)
+		}
+		var found bool
+		for _, message := range messages {
+			var command models.GameCommand
+			json.Unmarshal([]byte(message.Values["payload"].(string)), &command)
+			if command.GameID == gameID {
+				found = found || command.Type == models.GameCommandTimeout
+			}
+		}
+		if !found {
+			t.Errorf("no timeout command of %s in its mailbox", gameID)
+		}
+	}
+	if clocked, _ := client.GetClockedGames(); len(clocked) != 1 || clocked[0] != "running" {
+		t.Errorf("clocked games %v, want [running]", clocked)
+	}
+
+	// A deadline fires once, whoever looks at it next.
+	if fired, _ := client.FireExpiredGames(now); len(fired) != 0 {
+		t.Errorf("fired %v again", fired)
+	}
+}
+
+func TestFireExpiredGamesKeepsTheDeadlineWhenThePushFails(t *testing.T) {
+	client, _ := testRedisClient(t)
+	now := time.Now()
+	client.SetGameDeadline("game", now.Add(-time.Second))
+	// A mailbox key of the wrong type makes the push fail.
+	client.Client.Set(context.Background(), streamKey(GameCommandsQueue(GameCommandShard("game"))), "not a stream", 0)
+
+	if _, err := client.FireExpiredGames(now); err == nil {
+		t.Fatal("FireExpiredGames did not fail")
+	}
+	if clocked, _ := client.GetClockedGames(); len(clocked) != 1 || clocked[0] != "game" {
+		t.Errorf("clocked games %v, want the deadline kept", clocked)
+	}
+}
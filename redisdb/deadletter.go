@@ -0,0 +1,173 @@
+package redisdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// The dead letters of every queue are kept in a single stream, newest last, with the queue they came
+// from so they can be pushed back to it.
+const (
+	deadLettersKey       = "dead_letters"
+	deadLettersPageCount = 100
+)
+
+// ErrDeadLetterNotFound is returned for dead letters that were replayed, purged or never existed.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// AddDeadLetter stores the dead letter, its ID is set by Redis.
+func (r *RedisClient) AddDeadLetter(deadLetter models.DeadLetter) error {
+	err := r.Client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: deadLettersKey,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"queue":      deadLetter.Queue,
+			"worker":     deadLetter.Worker,
+			"consumer":   deadLetter.Consumer,
+			"message_id": deadLetter.MessageID,
+			"payload":    deadLetter.Payload,
+			"error":      deadLetter.Error,
+			"deliveries": deadLetter.Deliveries,
+			"dead_at":    deadLetter.DeadAt.UnixMilli(),
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to add dead letter of %s: %w", deadLetter.Queue, err)
+	}
+	return nil
+}
+
+// GetDeadLetters returns up to count dead letters, newest first, only the ones of the queue when it
+// is not empty.
+func (r *RedisClient) GetDeadLetters(queue string, count int) ([]models.DeadLetter, error) {
+	ctx := context.Background()
+	deadLetters := []models.DeadLetter{}
+	end := "+"
+	for len(deadLetters) < count {
+		entries, err := r.Client.XRevRangeN(ctx, deadLettersKey, end, "-", deadLettersPageCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("[RedisClient] - failed to get dead letters: %w", err)
+		}
+		for _, entry := range entries {
+			deadLetter := newDeadLetter(entry)
+			if queue == "" || deadLetter.Queue == queue {
+				deadLetters = append(deadLetters, deadLetter)
+			}
+			if len(deadLetters) == count {
+				break
+			}
+		}
+		if len(entries) < deadLettersPageCount {
+			break
+		}
+		end = "(" + entries[len(entries)-1].ID
+	}
+	return deadLetters, nil
+}
+
+// GetDeadLetter returns the dead letter with the ID.
+func (r *RedisClient) GetDeadLetter(id string) (*models.DeadLetter, error) {
+	entries, err := r.Client.XRange(context.Background(), deadLettersKey, id, id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to get dead letter %s: %w", id, err)
+	}
+	if len(entries) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	deadLetter := newDeadLetter(entries[0])
+	return &deadLetter, nil
+}
+
+// ReplayDeadLetter pushes the payload of the dead letter back to its queue and removes the dead letter.
+// The payload is only handled again by the group of the worker that failed, the other groups reading
+// the queue already handled it.
+func (r *RedisClient) ReplayDeadLetter(id string) (*models.DeadLetter, error) {
+	deadLetter, err := r.GetDeadLetter(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.StreamPushToGroup(deadLetter.Queue, deadLetter.Worker, []byte(deadLetter.Payload)); err != nil {
+		return nil, err
+	}
+	return deadLetter, r.DeleteDeadLetter(id)
+}
+
+// DeleteDeadLetter removes the dead letter with the ID.
+func (r *RedisClient) DeleteDeadLetter(id string) error {
+	deleted, err := r.Client.XDel(context.Background(), deadLettersKey, id).Result()
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to delete dead letter %s: %w", id, err)
+	}
+	if deleted == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// PurgeDeadLetters removes the dead letters of the queue, or all of them when queue is empty, and
+// returns how many were removed.
+func (r *RedisClient) PurgeDeadLetters(queue string) (int64, error) {
+	ctx := context.Background()
+	if queue == "" {
+		purged, err := r.Client.XLen(ctx, deadLettersKey).Result()
+		if err != nil {
+			return 0, fmt.Errorf("[RedisClient] - failed to count dead letters: %w", err)
+		}
+		if err := r.Client.Del(ctx, deadLettersKey).Err(); err != nil {
+			return 0, fmt.Errorf("[RedisClient] - failed to purge dead letters: %w", err)
+		}
+		return purged, nil
+	}
+	var purged int64
+	start := "-"
+	for {
+		entries, err := r.Client.XRangeN(ctx, deadLettersKey, start, "+", deadLettersPageCount).Result()
+		if err != nil {
+			return purged, fmt.Errorf("[RedisClient] - failed to get dead letters: %w", err)
+		}
+		var ids []string
+		for _, entry := range entries {
+			if newDeadLetter(entry).Queue == queue {
+				ids = append(ids, entry.ID)
+			}
+		}
+		if len(ids) > 0 {
+			deleted, err := r.Client.XDel(ctx, deadLettersKey, ids...).Result()
+			if err != nil {
+				return purged, fmt.Errorf("[RedisClient] - failed to purge dead letters of %s: %w", queue, err)
+			}
+			purged += deleted
+		}
+		if len(entries) < deadLettersPageCount {
+			return purged, nil
+		}
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
+
+func newDeadLetter(entry redis.XMessage) models.DeadLetter {
+	field := func(key string) string {
+		value, _ := entry.Values[key].(string)
+		return value
+	}
+	deliveries, _ := strconv.ParseInt(field("deliveries"), 10, 64)
+	deadAt, _ := strconv.ParseInt(field("dead_at"), 10, 64)
+	return models.DeadLetter{
+		ID:         entry.ID,
+		Queue:      field("queue"),
+		Worker:     field("worker"),
+		Consumer:   field("consumer"),
+		MessageID:  field("message_id"),
+		Payload:    field("payload"),
+		Error:      field("error"),
+		Deliveries: deliveries,
+		DeadAt:     time.UnixMilli(deadAt),
+	}
+}
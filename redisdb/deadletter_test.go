@@ -0,0 +1,113 @@
+package redisdb
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandleErrorDeadLettersTheMessage(t *testing.T) {
+	client, _ := testRedisClient(t)
+	queue := testStreamConsumer(t, client, "move_piece", "worker-1")
+	client.StreamPushGeneric("move_piece", []byte(`{"player_id": "player"}`))
+	message, _ := queue.Next(time.Second)
+
+	queue.Handle(*message, func(string) error { return errors.New("invalid move") })
+	deadLetters, err := client.GetDeadLetters("move_piece", 10)
+	if err != nil {
+		t.Fatalf("GetDeadLetters: %v", err)
+	}
+	if len(deadLetters) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(deadLetters))
+	}
+	got := deadLetters[0]
+	if got.Queue != "move_piece" || got.Worker != "worker" || got.Consumer != "worker-1" || got.MessageID != message.ID ||
+		got.Payload != message.Payload || got.Error != "invalid move" || got.Deliveries != 1 {
+		t.Errorf("dead letter %+v, want the message with the handle error", got)
+	}
+	if pending, _ := queue.Claim(0); len(pending) != 0 {
+		t.Errorf("%d messages left pending once dead lettered", len(pending))
+	}
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	client, _ := testRedisClient(t)
+	queue := testStreamConsumer(t, client, "move_piece", "worker-1")
+	client.StreamPushGeneric("move_piece", []byte(`{"player_id": "player"}`))
+	message, _ := queue.Next(time.Second)
+	queue.DeadLetter(*message, "invalid move")
+	deadLetters, _ := client.GetDeadLetters("", 10)
+	if len(deadLetters) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(deadLetters))
+	}
+
+	// The payload goes back to its queue, as a new message.
+	if _, err := client.ReplayDeadLetter(deadLetters[0].ID); err != nil {
+		t.Fatalf("ReplayDeadLetter: %v", err)
+	}
+	replayed, _ := queue.Next(time.Second)
+	if replayed == nil || replayed.Payload != message.Payload || replayed.ID == message.ID {
+		t.Errorf("replayed %+v, want the payload of %s again", replayed, message.ID)
+	}
+	if _, err := client.GetDeadLetter(deadLetters[0].ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("GetDeadLetter of a replayed dead letter = %v, want ErrDeadLetterNotFound", err)
+	}
+	if _, err := client.ReplayDeadLetter(deadLetters[0].ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("ReplayDeadLetter twice = %v, want ErrDeadLetterNotFound", err)
+	}
+}
+
+func TestReplayDeadLetterOnlyToTheFailingGroup(t *testing.T) {
+	client, _ := testRedisClient(t)
+	failing := testStreamConsumer(t, client, "create_game", "worker-1")
+	other := client.NewStreamConsumer("create_game", "other", "other-1")
+	if err := other.CreateGroup(); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	client.StreamPushGeneric("create_game", []byte(`{"id": "room"}`))
+	message, _ := failing.Next(time.Second)
+	failing.Handle(*message, func(string) error { return errors.New("failed") })
+	handled, _ := other.Next(time.Second)
+	if handled == nil || !other.Accept(*handled) {
+		t.Fatalf("the other group did not get the message: %+v", handled)
+	}
+	other.Ack(handled.ID)
+
+	deadLetters, _ := client.GetDeadLetters("create_game", 10)
+	if _, err := client.ReplayDeadLetter(deadLetters[0].ID); err != nil {
+		t.Fatalf("ReplayDeadLetter: %v", err)
+	}
+	replayed, _ := failing.Next(time.Second)
+	if replayed == nil || replayed.Payload != message.Payload || !failing.Accept(*replayed) {
+		t.Errorf("failing group got %+v, want the replayed payload accepted", replayed)
+	}
+	// The other group reads the replayed message too, it is acknowledged without being handled.
+	skipped, _ := other.Next(time.Second)
+	if skipped == nil || other.Accept(*skipped) {
+		t.Errorf("other group accepted %+v, want the replay skipped", skipped)
+	}
+	if pending, _ := other.Claim(0); len(pending) != 0 {
+		t.Errorf("%d messages left pending in the other group", len(pending))
+	}
+}
+
+func TestPurgeDeadLettersOfAQueue(t *testing.T) {
+	client, _ := testRedisClient(t)
+	for _, queueName := range []string{"move_piece", "leave_game", "move_piece"} {
+		queue := testStreamConsumer(t, client, queueName, "worker-1")
+		client.StreamPushGeneric(queueName, []byte(`{}`))
+		message, _ := queue.Next(time.Second)
+		queue.DeadLetter(*message, "failed")
+	}
+
+	purged, err := client.PurgeDeadLetters("move_piece")
+	if err != nil || purged != 2 {
+		t.Errorf("PurgeDeadLetters = %d, %v, want the 2 of move_piece", purged, err)
+	}
+	if left, _ := client.GetDeadLetters("", 10); len(left) != 1 || left[0].Queue != "leave_game" {
+		t.Errorf("dead letters left %+v, want the one of leave_game", left)
+	}
+	if purged, _ := client.PurgeDeadLetters(""); purged != 1 {
+		t.Errorf("PurgeDeadLetters of every queue = %d, want 1", purged)
+	}
+}
@@ -3,11 +3,13 @@ package redisdb
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/Lavizord/checkers-server/models"
+	"github.com/redis/go-redis/v9"
 )
 
 func (r *RedisClient) AddGame(game *models.Game) error {
@@ -23,25 +25,77 @@ func (r *RedisClient) AddGame(game *models.Game) error {
 	return r.Client.SAdd(context.Background(), betKey, game.ID).Err()
 }
 
+// ErrGameConflict is returned by UpdateGame when the game was saved by someone else since it was read.
+var ErrGameConflict = errors.New("[RedisClient] - game was updated since it was read")
+
+// ErrGameNotFound is returned by GetGame when the game was removed, or never saved.
+var ErrGameNotFound = errors.New("[RedisClient] - game not found")
+
+// gameUpdateRetries is how many times UpdateGameFunc reads the game again after a conflict.
+const gameUpdateRetries = 5
+
+// updateGameScript saves the game only when the stored revision is the one it was read with.
+// Returns -1 when the game does not exist and 0 on a conflict.
+var updateGameScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if not current then
+	return -1
+end
+local revision = cjson.decode(current)['revision'] or 0
+if revision ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+return 1
+`)
+
+// UpdateGame saves the game with its revision incremented, only if nobody saved it since it was read.
+// On ErrGameConflict nothing is saved, read the game again and redo the change, see UpdateGameFunc.
 func (r *RedisClient) UpdateGame(game *models.Game) error {
-	exists, err := r.GameExists(game.ID)
+	saved := *game
+	saved.Revision++
+	data, err := json.Marshal(saved)
 	if err != nil {
-		return fmt.Errorf("[RedisClient] - failed to check game existence: %v", err)
-	}
-	if !exists {
-		return fmt.Errorf("[RedisClient] - game with ID %s does not exist", game.ID)
+		return fmt.Errorf("[RedisClient] - failed to serialize game: %v", err)
 	}
-
-	data, err := json.Marshal(game)
+	result, err := updateGameScript.Run(context.Background(), r.Client, []string{"games"}, game.ID, game.Revision, data).Int()
 	if err != nil {
-		return fmt.Errorf("[RedisClient] - failed to serialize game: %v", err)
+		return fmt.Errorf("[RedisClient] - failed to update game: %v", err)
 	}
+	switch result {
+	case -1:
+		return fmt.Errorf("[RedisClient] - game with ID %s does not exist", game.ID)
+	case 0:
+		return ErrGameConflict
+	}
+	game.Revision = saved.Revision
+	return nil
+}
 
-	return r.Client.HSet(context.Background(), "games", game.ID, data).Err()
+// UpdateGameFunc reads the game, changes it with update and saves it with UpdateGame. When another
+// update was saved in between the game is read again and update runs again, update must only change
+// the game. Nothing is saved when update returns an error, the error is returned with the game read.
+func (r *RedisClient) UpdateGameFunc(gameID string, update func(game *models.Game) error) (*models.Game, error) {
+	for attempt := 0; attempt < gameUpdateRetries; attempt++ {
+		game, err := r.GetGame(gameID)
+		if err != nil {
+			return nil, err
+		}
+		if err := update(game); err != nil {
+			return game, err
+		}
+		if err := r.UpdateGame(game); !errors.Is(err, ErrGameConflict) {
+			return game, err
+		}
+	}
+	return nil, fmt.Errorf("[RedisClient] - game %s still conflicting after %d attempts: %w", gameID, gameUpdateRetries, ErrGameConflict)
 }
 
 func (r *RedisClient) GetGame(gameID string) (*models.Game, error) {
 	data, err := r.Client.HGet(context.Background(), "games", gameID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrGameNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("[RedisClient] - failed to get game: %v", err)
 	}
@@ -0,0 +1,128 @@
+package redisdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+// testStoredGame saves a new game and returns it.
+func testStoredGame(t *testing.T, client *RedisClient) *models.Game {
+	t.Helper()
+	game := &models.Game{ID: "game", BetValue: 1}
+	if err := client.AddGame(game); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	return game
+}
+
+func TestUpdateGameConflict(t *testing.T) {
+	client, _ := testRedisClient(t)
+	game := testStoredGame(t, client)
+	stale := *game
+
+	if err := client.UpdateGame(game); err != nil {
+		t.Fatalf("UpdateGame: %v", err)
+	}
+	if game.Revision != 1 {
+		t.Errorf("Revision = %d after a save, want 1", game.Revision)
+	}
+	if err := client.UpdateGame(&stale); !errors.Is(err, ErrGameConflict) {
+		t.Errorf("UpdateGame of a stale game = %v, want ErrGameConflict", err)
+	}
+	if err := client.UpdateGame(&models.Game{ID: "missing"}); err == nil || errors.Is(err, ErrGameConflict) {
+		t.Errorf("UpdateGame of a missing game = %v, want an error", err)
+	}
+}
+
+func TestUpdateGameFuncRetriesOnConflict(t *testing.T) {
+	client, _ := testRedisClient(t)
+	testStoredGame(t, client)
+
+	attempts := 0
+	game, err := client.UpdateGameFunc("game", func(game *models.Game) error {
+		attempts++
+		if attempts == 1 {
+			// Another update is saved between the read and the save.
+			other, _ := client.GetGame("game")
+			other.Turn = 7
+			if err := client.UpdateGame(other); err != nil {
+				t.Fatalf("UpdateGame: %v", err)
+			}
+		}
+		game.Winner = "black"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateGameFunc: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("update ran %d times, want 2", attempts)
+	}
+	saved, _ := client.GetGame("game")
+	if saved.Turn != 7 || saved.Winner != "black" || saved.Revision != 2 || game.Revision != 2 {
+		t.Errorf("saved Turn %d, Winner %s, Revision %d, returned Revision %d, want both updates in revision 2", saved.Turn, saved.Winner, saved.Revision, game.Revision)
+	}
+}
+
+func TestUpdateGameFuncGivesUp(t *testing.T) {
+	client, _ := testRedisClient(t)
+	testStoredGame(t, client)
+
+	attempts := 0
+	_, err := client.UpdateGameFunc("game", func(game *models.Game) error {
+		attempts++
+		other, _ := client.GetGame("game")
+		client.UpdateGame(other)
+		return nil
+	})
+	if !errors.Is(err, ErrGameConflict) || attempts != gameUpdateRetries {
+		t.Errorf("UpdateGameFunc = %v after %d attempts, want ErrGameConflict after %d", err, attempts, gameUpdateRetries)
+	}
+
+	// Nothing is saved when update fails.
+	failed := errors.New("invalid move")
+	if _, err := client.UpdateGameFunc("game", func(game *models.Game) error { return failed }); err != failed {
+		t.Errorf("UpdateGameFunc = %v, want the update error", err)
+	}
+	if _, err := client.UpdateGameFunc("missing", func(game *models.Game) error { return nil }); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("UpdateGameFunc of a missing game = %v, want ErrGameNotFound", err)
+	}
+}
+
+func TestUpdateGameFuncDropsAPartlyPlayedPath(t *testing.T) {
+	client, _ := testRedisClient(t)
+	game := testStoredGame(t, client)
+	blackID, whiteID := "black", "white"
+	board, _, err := models.ParseFEN(models.MultipleCaptureTestFEN, blackID, whiteID, models.DefaultVariant)
+	if err != nil {
+		t.Fatalf("ParseFEN: %v", err)
+	}
+	game.Board = *board
+	game.Players = []models.GamePlayer{{ID: blackID, Color: "b"}, {ID: whiteID, Color: "w"}}
+	game.CurrentPlayerID = blackID
+	if err := client.UpdateGame(game); err != nil {
+		t.Fatalf("UpdateGame: %v", err)
+	}
+
+	// The first hop captures D6, the second one lands on a square with nothing to capture on the way.
+	pieceID := board.Grid["C5"].PieceID
+	hops := []models.Move{
+		{PlayerID: blackID, PieceID: pieceID, From: "C5", To: "E7", IsCapture: true},
+		{PlayerID: blackID, PieceID: pieceID, From: "E7", To: "C9", IsCapture: true},
+	}
+	var played []models.MoveResult
+	_, err = client.UpdateGameFunc("game", func(game *models.Game) error {
+		var err error
+		played, _, err = game.PlayHops(hops)
+		return err
+	})
+	if err == nil || len(played) != 1 {
+		t.Fatalf("UpdateGameFunc = %v with %d hops played, want the error of the second hop", err, len(played))
+	}
+	saved, _ := client.GetGame("game")
+	if got, want := saved.Board.FEN(models.SideBlack), game.Board.FEN(models.SideBlack); got != want || len(saved.Moves) != 0 {
+		t.Errorf("stored board %s with %d moves, want %s with none", got, len(saved.Moves), want)
+	}
+}
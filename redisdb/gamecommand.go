@@ -0,0 +1,100 @@
+package redisdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// GameCommandShards is the number of game mailboxes, the commands of a game always go to the same one.
+// Each mailbox is read by a single gameworker at a time, see ClaimGameCommandShard.
+const GameCommandShards = 16
+
+// GameCommandShard returns the mailbox of the game.
+func GameCommandShard(gameID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(gameID))
+	return int(h.Sum32() % GameCommandShards)
+}
+
+// GameCommandsQueue is the queue of a game mailbox.
+func GameCommandsQueue(shard int) string {
+	return fmt.Sprintf("game_commands:%d", shard)
+}
+
+// ErrNotInGame is returned by SendGameCommand for the commands of a player that is not in a game.
+var ErrNotInGame = errors.New("[RedisClient] - the player is not in a game")
+
+// SendGameCommand builds a command of the game and adds it at the end of the game mailbox. The
+// services send the game events straight to the mailbox, so they reach it in the order they were sent.
+func (r *RedisClient) SendGameCommand(commandType, gameID string, data any) error {
+	if gameID == "" {
+		return ErrNotInGame
+	}
+	command, err := models.NewGameCommand(commandType, gameID, data)
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to serialize %s command: %v", commandType, err)
+	}
+	return r.PushGameCommand(command)
+}
+
+// PushGameCommand adds the command at the end of its game mailbox.
+func (r *RedisClient) PushGameCommand(command models.GameCommand) error {
+	data, err := json.Marshal(command)
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to serialize game command: %v", err)
+	}
+	return r.StreamPushGeneric(GameCommandsQueue(GameCommandShard(command.GameID)), data)
+}
+
+// claimLeaseScript takes a lease, of a mailbox or a bot, when it is free, or extends it for its owner.
+var claimLeaseScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if owner then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// releaseLeaseScript gives up a lease, only for its owner.
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func gameCommandShardOwnerKey(shard int) string {
+	return fmt.Sprintf("game_commands:%d:owner", shard)
+}
+
+// ClaimGameCommandShard takes or extends the lease of a mailbox for the owner, it returns false while
+// another gameworker holds it. The lease expires when the owner stops extending it.
+func (r *RedisClient) ClaimGameCommandShard(shard int, owner string, lease time.Duration) (bool, error) {
+	claimed, err := claimLeaseScript.Run(context.Background(), r.Client, []string{gameCommandShardOwnerKey(shard)}, owner, lease.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("[RedisClient] - failed to claim game command shard: %v", err)
+	}
+	return claimed == 1, nil
+}
+
+// ReleaseGameCommandShard gives up the lease of a mailbox, so another gameworker can take it over
+// without waiting for it to expire.
+func (r *RedisClient) ReleaseGameCommandShard(shard int, owner string) error {
+	err := releaseLeaseScript.Run(context.Background(), r.Client, []string{gameCommandShardOwnerKey(shard)}, owner).Err()
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to release game command shard: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package redisdb
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+func TestGameCommandShardLease(t *testing.T) {
+	client, server := testRedisClient(t)
+	lease := time.Second
+	claim := func(owner string) bool {
+		t.Helper()
+		claimed, err := client.ClaimGameCommandShard(0, owner, lease)
+		if err != nil {
+			t.Fatalf("ClaimGameCommandShard: %v", err)
+		}
+		return claimed
+	}
+
+	if !claim("a") {
+		t.Fatal("a could not claim a free mailbox")
+	}
+	if claim("b") {
+		t.Error("b claimed the mailbox held by a")
+	}
+	// Claiming again extends the lease of its owner.
+	server.FastForward(800 * time.Millisecond)
+	if !claim("a") {
+		t.Error("a could not extend its lease")
+	}
+	server.FastForward(800 * time.Millisecond)
+	if claim("b") {
+		t.Error("b claimed the mailbox while a extended its lease")
+	}
+	// Only the owner releases the lease.
+	client.ReleaseGameCommandShard(0, "b")
+	if claim("b") {
+		t.Error("b released the lease of a")
+	}
+	client.ReleaseGameCommandShard(0, "a")
+	if !claim("b") {
+		t.Error("b could not claim the mailbox released by a")
+	}
+	// A lease that is not extended expires.
+	server.FastForward(lease)
+	if !claim("a") {
+		t.Error("a could not claim the mailbox once the lease of b expired")
+	}
+}
+
+func TestSendGameCommand(t *testing.T) {
+	client, _ := testRedisClient(t)
+	mailbox := testStreamConsumer(t, client, GameCommandsQueue(GameCommandShard("game")), "gameworker")
+	move := models.Move{PlayerID: "black", From: "C3", To: "D4"}
+	if err := client.SendGameCommand(models.GameCommandMove, "game", move); err != nil {
+		t.Fatalf("SendGameCommand: %v", err)
+	}
+	message, err := mailbox.Next(time.Second)
+	if err != nil || message == nil {
+		t.Fatalf("Next: %v, %v", message, err)
+	}
+	var command models.GameCommand
+	json.Unmarshal([]byte(message.Payload), &command)
+	if command.Type != models.GameCommandMove || command.GameID != "game" || !strings.Contains(string(command.Data), `"from":"C3"`) {
+		t.Errorf("mailbox got %+v, want the move of the game", command)
+	}
+
+	if err := client.SendGameCommand(models.GameCommandMove, "", move); !errors.Is(err, ErrNotInGame) {
+		t.Errorf("SendGameCommand without a game = %v, want ErrNotInGame", err)
+	}
+}
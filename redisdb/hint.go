@@ -0,0 +1,33 @@
+package redisdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StartHintCooldown starts the hint cooldown of the player, it returns false when the player is still
+// waiting for the last one to expire.
+func (r *RedisClient) StartHintCooldown(playerID string, cooldown time.Duration) (bool, error) {
+	key := fmt.Sprintf("hint_cooldown:%s", playerID)
+	started, err := r.Client.SetNX(context.Background(), key, 1, cooldown).Result()
+	if err != nil {
+		return false, fmt.Errorf("[RedisClient] - failed to start hint cooldown: %v", err)
+	}
+	return started, nil
+}
+
+// IncrementHintsUsed counts a hint of the player in the game and returns the hints used so far.
+// The count expires a day after the first hint, long after the game is over.
+func (r *RedisClient) IncrementHintsUsed(gameID, playerID string) (int64, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf("hints_used:%s:%s", gameID, playerID)
+	used, err := r.Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("[RedisClient] - failed to increment hints used: %v", err)
+	}
+	if used == 1 {
+		r.Client.Expire(ctx, key, 24*time.Hour)
+	}
+	return used, nil
+}
@@ -60,6 +60,15 @@ func (r *RedisClient) GetNumPlayers() (int64, error) {
 	return numPlayers, nil
 }
 
+// PlayerExists reports if the player is stored.
+func (r *RedisClient) PlayerExists(playerID string) (bool, error) {
+	exists, err := r.Client.HExists(context.Background(), "players", playerID).Result()
+	if err != nil {
+		return false, fmt.Errorf("[RedisClient] - failed to check if player exists: %v", err)
+	}
+	return exists, nil
+}
+
 func (r *RedisClient) RemovePlayer(playerID string) error {
 	exists, err := r.Client.HExists(context.Background(), "players", playerID).Result()
 	if err != nil {
@@ -172,7 +172,8 @@ func (r *RedisClient) UnsubscribePlayerChannel(player models.Player) {
 	//log.Printf("[RedisClii] (UnsubscribePlayerChannel) - Deleted subscription of [%d] and [%v]\n", player.Name, channel)
 	r.mu.Unlock()
 
-	if err := pubsub.Unsubscribe(context.Background(), channel); err != nil {
+	// Closing the subscription releases its connection and ends the goroutine reading it.
+	if err := pubsub.Close(); err != nil {
 		log.Println("Error unsubscribing from", channel, ":", err)
 	} else {
 		//log.Printf("[RedisClii] (UnsubscribePlayerChannel) - Unsubscribe of [%v] and [%v]\n", player.Name, channel)
@@ -190,7 +191,7 @@ func (r *RedisClient) Unsubscribe(channel string) {
 	delete(r.Subscriptions, channel)
 	r.mu.Unlock()
 
-	if err := pubsub.Unsubscribe(context.Background(), channel); err != nil {
+	if err := pubsub.Close(); err != nil {
 		log.Println("Error unsubscribing from", channel, ":", err)
 	}
 }
@@ -210,22 +211,27 @@ func (r *RedisClient) DisconnectPlayer(playerID string) {
 	r.PublishToPlayerID(playerID, message)
 }
 
+// RemovePlayerFromQueue deletes the entries of the player from the matchmaking queue stream. An entry a
+// roomworker is pairing stays pending for it, the roomworker drops the player once it sees it left.
 func (r *RedisClient) RemovePlayerFromQueue(queueName string, player *models.Player) error {
 	ctx := context.Background()
-	// Serialize the player to match stored format
-	data, err := json.Marshal(player)
+	entries, err := r.Client.XRange(ctx, streamKey(queueName), "-", "+").Result()
 	if err != nil {
-		return fmt.Errorf("[RedisClient] - failed to serialize player: %v", err)
+		return fmt.Errorf("[RedisClient] - failed to read queue %s: %v", queueName, err)
 	}
-	// Remove the exact serialized JSON string from the queue
-	removed, err := r.Client.LRem(ctx, queueName, 1, string(data)).Result()
-	if err != nil {
-		return fmt.Errorf("[RedisClient] - failed to remove player: %v", err)
+	var ids []string
+	for _, entry := range entries {
+		var queued models.Player
+		if json.Unmarshal([]byte(streamPayload(entry)), &queued) == nil && queued.ID == player.ID {
+			ids = append(ids, entry.ID)
+		}
 	}
-	if removed == 0 {
+	if len(ids) == 0 {
 		return fmt.Errorf("[RedisClient] - player not found in queue %s", queueName)
 	}
-
+	if err := r.Client.XDel(ctx, streamKey(queueName), ids...).Err(); err != nil {
+		return fmt.Errorf("[RedisClient] - failed to remove player: %v", err)
+	}
 	return nil
 }
 
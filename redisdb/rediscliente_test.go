@@ -0,0 +1,55 @@
+package redisdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testRedisClient returns a client of an in-memory Redis, closed at the end of the test.
+func testRedisClient(t *testing.T) (*RedisClient, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client, err := NewRedisClient(server.Addr(), "", "")
+	if err != nil {
+		t.Fatalf("NewRedisClient: %v", err)
+	}
+	t.Cleanup(func() { client.Client.Close() })
+	return client, server
+}
+
+func TestUnsubscribePlayerChannelClosesTheSubscription(t *testing.T) {
+	client, server := testRedisClient(t)
+	connections := server.CurrentConnectionCount()
+	player := models.Player{ID: "bot"}
+	received := make(chan string, 1)
+	client.SubscribePlayerChannel(player, func(data string) { received <- data })
+
+	// The subscription is set up in the background, publish until it gets the message.
+	waitFor(t, "the subscription to get a message", func() bool {
+		client.PublishToPlayer(player, "turn_switch")
+		return len(received) > 0
+	})
+
+	// The subscription has its own connection, it must be closed with it.
+	client.UnsubscribePlayerChannel(player)
+	if len(client.Subscriptions) != 0 {
+		t.Errorf("%d subscriptions left, want none", len(client.Subscriptions))
+	}
+	waitFor(t, "the subscription connection to close", func() bool {
+		return server.CurrentConnectionCount() == connections
+	})
+}
+
+// waitFor polls cond for up to two seconds, for the work Redis clients do in the background.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
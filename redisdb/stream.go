@@ -0,0 +1,356 @@
+package redisdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// The queues between the services are Redis streams read by consumer groups. Every service reads a
+// queue in its own group, each message goes to a single consumer of the group and stays pending until
+// the consumer acknowledges it, so the messages of a worker that stops are not lost: they are claimed
+// by the other consumers once they are pending for StreamClaimIdle. Messages a worker fails to handle,
+// and the ones that keep being claimed without being acknowledged, are moved to the dead letters.
+const (
+	StreamClaimIdle     = 30 * time.Second
+	StreamMaxDeliveries = 5
+	streamMaxLen        = 100000 // Streams are trimmed to about this many messages.
+	streamClaimCount    = 100
+)
+
+// StreamMessage is a message read from a queue.
+type StreamMessage struct {
+	ID         string
+	Payload    string
+	Deliveries int64  // Times the message was delivered, including this one.
+	Group      string // Consumer group the message is for, the other groups skip it. Empty for every group.
+}
+
+func streamKey(queue string) string {
+	return "stream:" + queue
+}
+
+// ConsumerName identifies this process in the consumer groups.
+func ConsumerName() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// StreamPush - Push serialized player to a queue
+func (r *RedisClient) StreamPush(queue string, player *models.Player) error {
+	data, err := json.Marshal(player)
+	if err != nil {
+		return err
+	}
+	return r.StreamPushGeneric(queue, data)
+}
+
+// StreamPushGeneric - Push data to a queue
+func (r *RedisClient) StreamPushGeneric(queue string, data []byte) error {
+	return r.StreamPushToGroup(queue, "", data)
+}
+
+// StreamPushToGroup pushes data to a queue for a single consumer group, the other groups reading the
+// queue acknowledge the message without handling it. An empty group pushes to every group.
+func (r *RedisClient) StreamPushToGroup(queue, group string, data []byte) error {
+	err := r.Client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: streamKey(queue),
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: streamValues(string(data), group),
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to push to %s: %w", queue, err)
+	}
+	return nil
+}
+
+// StreamConsumer reads a queue as a consumer of a group.
+type StreamConsumer struct {
+	Queue    string
+	Group    string
+	Consumer string
+	// DeleteAcked deletes the messages from the queue once acknowledged, for the queues that only
+	// hold what is still waiting, like the matchmaking queues.
+	DeleteAcked bool
+	client      *RedisClient
+	claimed     []StreamMessage // Claimed by Receive and not handed out yet.
+	lastClaim   time.Time
+}
+
+func (r *RedisClient) NewStreamConsumer(queue, group, consumer string) *StreamConsumer {
+	return &StreamConsumer{Queue: queue, Group: group, Consumer: consumer, client: r}
+}
+
+// CreateGroup creates the group of the consumer when it does not exist yet, the group starts at the
+// beginning of the queue so the messages pushed before it was created are read too.
+func (c *StreamConsumer) CreateGroup() error {
+	err := c.client.Client.XGroupCreateMkStream(context.Background(), streamKey(c.Queue), c.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("[RedisClient] - failed to create group %s of %s: %w", c.Group, c.Queue, err)
+	}
+	return nil
+}
+
+// Next waits up to block for a message no consumer of the group has read yet, nil when there was none.
+func (c *StreamConsumer) Next(block time.Duration) (*StreamMessage, error) {
+	streams, err := c.client.Client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    c.Group,
+		Consumer: c.Consumer,
+		Streams:  []string{streamKey(c.Queue), ">"},
+		Count:    1,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to read %s: %w", c.Queue, err)
+	}
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			return &StreamMessage{ID: message.ID, Payload: streamPayload(message), Deliveries: 1, Group: streamGroup(message)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Claim takes over the messages of the group pending for at least minIdle, oldest first.
+func (c *StreamConsumer) Claim(minIdle time.Duration) ([]StreamMessage, error) {
+	ctx := context.Background()
+	pending, err := c.client.Client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: streamKey(c.Queue),
+		Group:  c.Group,
+		Idle:   minIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  streamClaimCount,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to get pending messages of %s: %w", c.Queue, err)
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(pending))
+	deliveries := make(map[string]int64, len(pending))
+	for _, p := range pending {
+		ids = append(ids, p.ID)
+		deliveries[p.ID] = p.RetryCount
+	}
+	claimed, err := c.client.Client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   streamKey(c.Queue),
+		Group:    c.Group,
+		Consumer: c.Consumer,
+		MinIdle:  minIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("[RedisClient] - failed to claim pending messages of %s: %w", c.Queue, err)
+	}
+	messages := make([]StreamMessage, 0, len(claimed))
+	for _, message := range claimed {
+		messages = append(messages, StreamMessage{ID: message.ID, Payload: streamPayload(message), Deliveries: deliveries[message.ID] + 1, Group: streamGroup(message)})
+	}
+	return messages, nil
+}
+
+// Receive waits up to block for the next message to handle. The messages other consumers of the group
+// left pending for StreamClaimIdle are claimed every StreamClaimIdle/2 and come before the new ones.
+func (c *StreamConsumer) Receive(block time.Duration) (*StreamMessage, error) {
+	if time.Since(c.lastClaim) >= StreamClaimIdle/2 {
+		c.lastClaim = time.Now()
+		claimed, err := c.Claim(StreamClaimIdle)
+		if err != nil {
+			return nil, err
+		}
+		c.claimed = append(c.claimed, claimed...)
+	}
+	if len(c.claimed) > 0 {
+		message := c.claimed[0]
+		c.claimed = c.claimed[1:]
+		return &message, nil
+	}
+	return c.Next(block)
+}
+
+// Ack tells the group the message was handled.
+func (c *StreamConsumer) Ack(id string) error {
+	ctx := context.Background()
+	_, err := c.client.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		c.ack(ctx, pipe, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to ack %s of %s: %w", id, c.Queue, err)
+	}
+	return nil
+}
+
+// Requeue pushes the message back to the end of the queue and acknowledges it, in one step, for the
+// messages that wait for something else, like a player waiting for an opponent.
+func (c *StreamConsumer) Requeue(message StreamMessage) error {
+	ctx := context.Background()
+	_, err := c.client.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: streamKey(c.Queue),
+			MaxLen: streamMaxLen,
+			Approx: true,
+			Values: streamValues(message.Payload, message.Group),
+		})
+		c.ack(ctx, pipe, message.ID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("[RedisClient] - failed to requeue %s of %s: %w", message.ID, c.Queue, err)
+	}
+	return nil
+}
+
+func (c *StreamConsumer) ack(ctx context.Context, pipe redis.Pipeliner, id string) {
+	pipe.XAck(ctx, streamKey(c.Queue), c.Group, id)
+	if c.DeleteAcked {
+		pipe.XDel(ctx, streamKey(c.Queue), id)
+	}
+}
+
+// DeadLetter moves the message to the dead letters with the reason it could not be handled.
+func (c *StreamConsumer) DeadLetter(message StreamMessage, reason string) error {
+	log.Printf("[RedisClient] (%s) - Dead lettering %s: %s\n", c.Queue, message.ID, reason)
+	err := c.client.AddDeadLetter(models.DeadLetter{
+		Queue:      c.Queue,
+		Worker:     c.Group,
+		Consumer:   c.Consumer,
+		MessageID:  message.ID,
+		Payload:    message.Payload,
+		Error:      reason,
+		Deliveries: message.Deliveries,
+		DeadAt:     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return c.Ack(message.ID)
+}
+
+// Accept tells whether the message is to be handled. Messages delivered more than StreamMaxDeliveries
+// times are dead lettered instead, and the ones trimmed from the queue while pending or pushed for
+// another group are only acknowledged.
+func (c *StreamConsumer) Accept(message StreamMessage) bool {
+	var err error
+	switch {
+	case message.Payload == "":
+		err = c.Ack(message.ID)
+	case message.Group != "" && message.Group != c.Group:
+		// Not deleted with DeleteAcked, the message is still waiting for its group.
+		err = c.client.Client.XAck(context.Background(), streamKey(c.Queue), c.Group, message.ID).Err()
+	case message.Deliveries > StreamMaxDeliveries:
+		err = c.DeadLetter(message, fmt.Sprintf("not acknowledged after %d deliveries", message.Deliveries-1))
+	default:
+		return true
+	}
+	if err != nil {
+		log.Printf("[RedisClient] (%s) - %v\n", c.Queue, err)
+	}
+	return false
+}
+
+// Finish acknowledges a handled message, or dead letters it when handleErr is not nil.
+func (c *StreamConsumer) Finish(message StreamMessage, handleErr error) {
+	var err error
+	if handleErr != nil {
+		err = c.DeadLetter(message, handleErr.Error())
+	} else {
+		err = c.Ack(message.ID)
+	}
+	if err != nil {
+		log.Printf("[RedisClient] (%s) - %v\n", c.Queue, err)
+	}
+}
+
+// Handle runs handle on the accepted message and finishes it, see Accept and Finish.
+func (c *StreamConsumer) Handle(message StreamMessage, handle func(payload string) error) {
+	c.HandleOwned(message, handle, nil)
+}
+
+// HandleOwned is Handle for the queues read by a single consumer at a time, like the game mailboxes.
+// owned is checked once handle returns: when the consumer no longer owns the queue the message is left
+// pending, for the new owner to handle again, and HandleOwned returns false.
+func (c *StreamConsumer) HandleOwned(message StreamMessage, handle func(payload string) error, owned func() bool) bool {
+	if !c.Accept(message) {
+		return true
+	}
+	handleErr := handle(message.Payload)
+	if owned != nil && !owned() {
+		log.Printf("[RedisClient] (%s) - Lost the queue while handling %s, left pending for its new owner\n", c.Queue, message.ID)
+		return false
+	}
+	c.Finish(message, handleErr)
+	return true
+}
+
+// Run reads the queue until ctx is done, handling the new messages and claiming the ones other
+// consumers of the group left pending. The message being handled when ctx is done is finished first.
+func (c *StreamConsumer) Run(ctx context.Context, handle func(payload string) error) {
+	for ctx.Err() == nil {
+		err := c.CreateGroup()
+		if err == nil {
+			break
+		}
+		log.Printf("[RedisClient] (%s) - %v\n", c.Queue, err)
+		time.Sleep(time.Second)
+	}
+	for ctx.Err() == nil {
+		message, err := c.Receive(time.Second)
+		if err != nil {
+			log.Printf("[RedisClient] (%s) - %v\n", c.Queue, err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if message != nil {
+			c.Handle(*message, handle)
+		}
+	}
+}
+
+// ConsumeStream reads the queue in the group until ctx is done, see StreamConsumer.Run.
+func (r *RedisClient) ConsumeStream(ctx context.Context, queue, group, consumer string, handle func(payload string) error) {
+	r.NewStreamConsumer(queue, group, consumer).Run(ctx, handle)
+}
+
+// ConsumePlayers is ConsumeStream for the queues of serialized players.
+func (r *RedisClient) ConsumePlayers(ctx context.Context, queue, group, consumer string, handle func(player *models.Player) error) {
+	r.ConsumeStream(ctx, queue, group, consumer, func(payload string) error {
+		var player models.Player
+		if err := json.Unmarshal([]byte(payload), &player); err != nil {
+			return fmt.Errorf("failed to deserialize player: %w", err)
+		}
+		return handle(&player)
+	})
+}
+
+func streamPayload(message redis.XMessage) string {
+	payload, _ := message.Values["payload"].(string)
+	return payload
+}
+
+func streamGroup(message redis.XMessage) string {
+	group, _ := message.Values["group"].(string)
+	return group
+}
+
+func streamValues(payload, group string) map[string]interface{} {
+	values := map[string]interface{}{"payload": payload}
+	if group != "" {
+		values["group"] = group
+	}
+	return values
+}
@@ -0,0 +1,174 @@
+package redisdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Lavizord/checkers-server/models"
+)
+
+// testStreamConsumer returns a consumer of the queue with its group created.
+func testStreamConsumer(t *testing.T, client *RedisClient, queue, consumer string) *StreamConsumer {
+	t.Helper()
+	c := client.NewStreamConsumer(queue, "worker", consumer)
+	if err := c.CreateGroup(); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	return c
+}
+
+func TestStreamConsumerRequeueDeletesAckedMessages(t *testing.T) {
+	client, _ := testRedisClient(t)
+	queue := testStreamConsumer(t, client, "queue:1.000000", "roomworker")
+	queue.DeleteAcked = true
+	client.StreamPush(queue.Queue, &models.Player{ID: "player"})
+
+	message, err := queue.Next(time.Second)
+	if err != nil || message == nil {
+		t.Fatalf("Next: %v, %v", message, err)
+	}
+	if err := queue.Requeue(*message); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	requeued, err := queue.Next(time.Second)
+	if err != nil || requeued == nil {
+		t.Fatalf("Next after Requeue: %v, %v", requeued, err)
+	}
+	if requeued.ID == message.ID || requeued.Payload != message.Payload {
+		t.Errorf("requeued %+v, want %+v with a new ID", requeued, message)
+	}
+	if err := queue.Ack(requeued.ID); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	ctx := context.Background()
+	if length := client.Client.XLen(ctx, streamKey(queue.Queue)).Val(); length != 0 {
+		t.Errorf("%d messages left in the queue, want none", length)
+	}
+	if pending := client.Client.XPending(ctx, streamKey(queue.Queue), queue.Group).Val(); pending.Count != 0 {
+		t.Errorf("%d messages left pending, want none", pending.Count)
+	}
+}
+
+func TestRemovePlayerFromQueue(t *testing.T) {
+	client, _ := testRedisClient(t)
+	queueName := "queue:1.000000"
+	client.StreamPush(queueName, &models.Player{ID: "leaving", Status: models.StatusInQueue})
+	client.StreamPush(queueName, &models.Player{ID: "waiting", Status: models.StatusInQueue})
+
+	// The player is found by its ID, its status changed since it was queued.
+	if err := client.RemovePlayerFromQueue(queueName, &models.Player{ID: "leaving", Status: models.StatusOnline}); err != nil {
+		t.Fatalf("RemovePlayerFromQueue: %v", err)
+	}
+	entries := client.Client.XRange(context.Background(), streamKey(queueName), "-", "+").Val()
+	if len(entries) != 1 || !strings.Contains(streamPayload(entries[0]), `"waiting"`) {
+		t.Errorf("queue %v, want only the waiting player", entries)
+	}
+	if err := client.RemovePlayerFromQueue(queueName, &models.Player{ID: "leaving"}); err == nil {
+		t.Error("removed a player that is not in the queue")
+	}
+}
+
+func TestHandleOwnedAcksOnlyWhileOwned(t *testing.T) {
+	client, server := testRedisClient(t)
+	shard, lease := GameCommandShard("game"), time.Second
+	command, _ := models.NewGameCommand(models.GameCommandTimeout, "game", nil)
+	if err := client.PushGameCommand(command); err != nil {
+		t.Fatalf("PushGameCommand: %v", err)
+	}
+	owned := func(owner string) func() bool {
+		return func() bool {
+			claimed, _ := client.ClaimGameCommandShard(shard, owner, lease)
+			return claimed
+		}
+	}
+
+	// a runs the command but its lease expires and b takes the mailbox before it is done.
+	a := testStreamConsumer(t, client, GameCommandsQueue(shard), "a")
+	owned("a")()
+	message, _ := a.Next(time.Second)
+	if message == nil {
+		t.Fatal("no command in the mailbox")
+	}
+	if a.HandleOwned(*message, func(string) error {
+		server.FastForward(lease)
+		owned("b")()
+		return nil
+	}, owned("a")) {
+		t.Error("HandleOwned = true for a command handled after the lease was lost")
+	}
+
+	// The command is left pending for b, which handles it again.
+	b := testStreamConsumer(t, client, GameCommandsQueue(shard), "b")
+	pending, _ := b.Claim(0)
+	if len(pending) != 1 || pending[0].ID != message.ID {
+		t.Fatalf("b claimed %v, want the command a lost", pending)
+	}
+	if !b.HandleOwned(pending[0], func(string) error { return nil }, owned("b")) {
+		t.Error("HandleOwned = false for the owner")
+	}
+	if pending, _ := b.Claim(0); len(pending) != 0 {
+		t.Errorf("%d commands left pending after b handled them", len(pending))
+	}
+}
+
+func TestStreamConsumerClaimsIdleMessages(t *testing.T) {
+	client, server := testRedisClient(t)
+	now := time.Now()
+	server.SetTime(now) // The pending messages idle on the server time.
+	stopped := testStreamConsumer(t, client, "move_piece", "stopped")
+	running := testStreamConsumer(t, client, "move_piece", "running")
+	client.StreamPushGeneric("move_piece", []byte(`{"player_id": "player"}`))
+	message, _ := stopped.Next(time.Second)
+	if message == nil {
+		t.Fatal("no message in the queue")
+	}
+
+	// The message is only claimed once it is pending for StreamClaimIdle.
+	if claimed, _ := running.Receive(time.Millisecond); claimed != nil {
+		t.Errorf("claimed %+v before StreamClaimIdle", claimed)
+	}
+	server.SetTime(now.Add(StreamClaimIdle))
+	running.lastClaim = time.Time{}
+	claimed, err := running.Receive(time.Millisecond)
+	if err != nil || claimed == nil {
+		t.Fatalf("Receive = %v, %v, want the message of the stopped consumer", claimed, err)
+	}
+	if claimed.ID != message.ID || claimed.Deliveries != 2 {
+		t.Errorf("claimed %+v, want %s on its second delivery", claimed, message.ID)
+	}
+}
+
+func TestStreamConsumerDeadLettersAfterMaxDeliveries(t *testing.T) {
+	client, _ := testRedisClient(t)
+	queue := testStreamConsumer(t, client, "move_piece", "worker")
+	client.StreamPushGeneric("move_piece", []byte(`{"player_id": "player"}`))
+	message, _ := queue.Next(time.Second)
+	for deliveries := int64(1); deliveries <= StreamMaxDeliveries; deliveries++ {
+		if !queue.Accept(*message) {
+			t.Fatalf("delivery %d was not accepted", deliveries)
+		}
+		claimed, _ := queue.Claim(0)
+		if len(claimed) != 1 {
+			t.Fatalf("claimed %v, want the message left pending", claimed)
+		}
+		message = &claimed[0]
+	}
+
+	handled := false
+	queue.Handle(*message, func(string) error {
+		handled = true
+		return nil
+	})
+	if handled {
+		t.Errorf("handled the message on delivery %d", message.Deliveries)
+	}
+	deadLetters, _ := client.GetDeadLetters("move_piece", 10)
+	if len(deadLetters) != 1 || deadLetters[0].MessageID != message.ID || deadLetters[0].Deliveries != StreamMaxDeliveries+1 {
+		t.Errorf("dead letters %+v, want the message after %d deliveries", deadLetters, StreamMaxDeliveries+1)
+	}
+	if pending, _ := queue.Claim(0); len(pending) != 0 {
+		t.Errorf("%d messages left pending once dead lettered", len(pending))
+	}
+}
@@ -0,0 +1,124 @@
+// Command replaytool replays finished games stored in Postgres through the rules engine and reports
+// the games with an illegal move, a final position that does not match or a wrong winner.
+//
+//	CONFIG_PATH=config/config.json go run ./replaytool -from 2025-03-01 -to 2025-04-01
+//	CONFIG_PATH=config/config.json go run ./replaytool -game <game id> -json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Lavizord/checkers-server/config"
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/Lavizord/checkers-server/postgrescli"
+)
+
+var name = "replaytool"
+
+func main() {
+	from := flag.String("from", "", "first day of the games to replay, YYYY-MM-DD")
+	to := flag.String("to", "", "day after the last day of the games to replay, YYYY-MM-DD, defaults to the day after from")
+	gameID := flag.String("game", "", "replay a single game by ID")
+	asJSON := flag.Bool("json", false, "print every report as a JSON line")
+	all := flag.Bool("all", false, "print the valid games too")
+	flag.Parse()
+
+	gameIDs := []string{*gameID}
+	config.LoadConfig()
+	postgresClient, err := postgrescli.NewPostgresCli(
+		config.Cfg.Postgres.User,
+		config.Cfg.Postgres.Password,
+		config.Cfg.Postgres.DBName,
+		config.Cfg.Postgres.Host,
+		config.Cfg.Postgres.Port,
+		config.Cfg.Postgres.Ssl,
+	)
+	if err != nil {
+		log.Fatalf("[%s] - Error initializing POSTGRES client: %v\n", name, err)
+	}
+	defer postgresClient.Close()
+
+	if *gameID == "" {
+		fromDate, toDate, err := parseDateRange(*from, *to)
+		if err != nil {
+			log.Fatalf("[%s] - %v\n", name, err)
+		}
+		gameIDs, err = postgresClient.FetchGameIDs(fromDate, toDate)
+		if err != nil {
+			log.Fatalf("[%s] - Error fetching games: %v\n", name, err)
+		}
+		log.Printf("[%s] - Replaying %d games from %s to %s\n", name, len(gameIDs), *from, toDate.Format(time.DateOnly))
+	}
+
+	invalid := 0
+	for _, id := range gameIDs {
+		game, reason, err := postgresClient.FetchGame(id)
+		if err != nil {
+			log.Printf("[%s] - Error fetching game %s: %v\n", name, id, err)
+			invalid++
+			continue
+		}
+		report := models.ReplayGame(*game, reason)
+		if !report.Valid {
+			invalid++
+		}
+		if report.Valid && !*all {
+			continue
+		}
+		if *asJSON {
+			data, _ := json.Marshal(report)
+			fmt.Println(string(data))
+			continue
+		}
+		printReport(report)
+	}
+	log.Printf("[%s] - %d games replayed, %d invalid\n", name, len(gameIDs), invalid)
+	if invalid > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	if from == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("-from or -game is required")
+	}
+	fromDate, err := time.Parse(time.DateOnly, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid -from date: %v", err)
+	}
+	toDate := fromDate.AddDate(0, 0, 1)
+	if to != "" {
+		toDate, err = time.Parse(time.DateOnly, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -to date: %v", err)
+		}
+	}
+	return fromDate, toDate, nil
+}
+
+func printReport(report models.ReplayReport) {
+	status := "OK"
+	if !report.Valid {
+		status = "INVALID"
+	}
+	fmt.Printf("%s %s (%s) - %d moves replayed, %d turns passed on timeout\n", status, report.GameID, report.Variant, report.MovesReplayed, report.Passes)
+	if report.IllegalMove != nil {
+		move := report.IllegalMove
+		fmt.Printf("  illegal move #%d %s-%s by %s: %s %s\n", move.Index, move.Move.From, move.Move.To, move.Move.PlayerID, move.Code, move.Reason)
+	}
+	fmt.Printf("  final position: %s\n", report.FinalFEN)
+	if report.FENMismatch {
+		fmt.Printf("  stored position: %s\n", report.StoredFEN)
+	}
+	if report.Winner.Mismatch {
+		fmt.Printf("  winner mismatch: stored %q, expected %q\n", report.Winner.Stored, report.Winner.Expected)
+	}
+	for _, e := range report.Errors {
+		fmt.Printf("  %s\n", e)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Lavizord/checkers-server/models"
+	"github.com/gorilla/mux"
+)
+
+// fetchPlayerGame returns the finished game of the {id} route variable when the request comes from one
+// of its players. Players send the token and sessionid the gamelaunch put in their game URL, the
+// session is checked like the wsapi does. On failure the error response is written and ok is false.
+func fetchPlayerGame(w http.ResponseWriter, r *http.Request) (game *models.Game, reason string, ok bool) {
+	gameID := mux.Vars(r)["id"]
+	session, err := validateSession(r.URL.Query().Get("token"), r.URL.Query().Get("sessionid"))
+	if err != nil {
+		log.Printf("[%s] - (Game Auth) - Unauthorized request for game %s: %v\n", name, gameID, err)
+		respondWithJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return nil, "", false
+	}
+	game, reason, err = postgresClient.FetchGame(gameID)
+	if err != nil {
+		log.Printf("[%s] - (Game Auth) - Error fetching game %s: %v\n", name, gameID, err)
+		respondWithJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Game not found: %s", gameID)})
+		return nil, "", false
+	}
+	for _, player := range game.Players {
+		if player.SessionID == session.ID {
+			return game, reason, true
+		}
+	}
+	// Same answer as a missing game, the IDs of other players' games are not confirmed.
+	respondWithJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Game not found: %s", gameID)})
+	return nil, "", false
+}
+
+// requireSession lets through the requests of players with a valid session, that send the token and
+// sessionid of their game URL as query parameters.
+func requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := validateSession(r.URL.Query().Get("token"), r.URL.Query().Get("sessionid")); err != nil {
+			log.Printf("[%s] - (Session Auth) - Unauthorized %s %s: %v\n", name, r.Method, r.URL.Path, err)
+			respondWithJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func validateSession(token, sessionID string) (*models.Session, error) {
+	if token == "" || sessionID == "" {
+		return nil, fmt.Errorf("[Session] - token and sessionid are required")
+	}
+	session, err := redisClient.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("[Session] - failed to fetch session: %v", err)
+	}
+	if session.Token != token {
+		return nil, fmt.Errorf("[Session] - token mismatch")
+	}
+	if session.OperatorIdentifier.OperatorName != "TestOp" && session.IsTokenExpired() {
+		return nil, fmt.Errorf("[Session] - token expired")
+	}
+	return session, nil
+}
+
+// requireAdmin lets through the requests of the operators, that send the restapi admin token as
+// "Authorization: Bearer <token>". Without an admin token configured every request is refused.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if adminToken == "" || !found || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			log.Printf("[%s] - (Admin Auth) - Unauthorized %s %s from %s\n", name, r.Method, r.URL.Path, r.RemoteAddr)
+			respondWithJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
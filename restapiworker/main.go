@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/Lavizord/checkers-server/config"
+	"github.com/Lavizord/checkers-server/engine"
 	"github.com/Lavizord/checkers-server/interfaces"
 	"github.com/Lavizord/checkers-server/models"
 	"github.com/Lavizord/checkers-server/postgrescli"
@@ -19,6 +28,19 @@ var postgresClient *postgrescli.PostgresCli
 var redisClient *redisdb.RedisClient
 var name = "restapi"
 
+// The analyses are CPU heavy, at most maxAnalyses run at the same time and the other requests are
+// refused until one finishes. Analyses of finished games are cached for analysisCacheTTL.
+const analysisCacheTTL = 7 * 24 * time.Hour
+
+var analyses chan struct{}
+
+// maxPDNSize is the largest PDN accepted by the import, far above the longest game.
+const maxPDNSize = 64 << 10
+
+// adminToken is the token of the operator routes, from the ADMIN_TOKEN environment variable or the
+// restapi admin_token setting.
+var adminToken string
+
 func init() {
 	config.LoadConfig()
 
@@ -41,6 +63,20 @@ func init() {
 		log.Fatalf("[PostgreSQL] Error initializing POSTGRES client: %v\n", err)
 	}
 	postgresClient = sqlcliente
+
+	maxAnalyses := config.Cfg.Services[name].MaxAnalyses
+	if maxAnalyses <= 0 {
+		maxAnalyses = 2
+	}
+	analyses = make(chan struct{}, maxAnalyses)
+
+	adminToken = os.Getenv("ADMIN_TOKEN")
+	if adminToken == "" {
+		adminToken = config.Cfg.Services[name].AdminToken
+	}
+	if adminToken == "" {
+		log.Printf("[%s] - No admin token set, the operator routes are disabled\n", name)
+	}
 }
 
 func gameLaunchHandler(w http.ResponseWriter, r *http.Request) {
@@ -93,6 +129,200 @@ func gameLaunchHandler(w http.ResponseWriter, r *http.Request) {
 	module.HandleGameLaunch(w, r, req, *operator, redisClient, postgresClient)
 }
 
+// gamePDNHandler exports a finished game in PDN, for its players.
+func gamePDNHandler(w http.ResponseWriter, r *http.Request) {
+	game, reason, ok := fetchPlayerGame(w, r)
+	if !ok {
+		return
+	}
+	respondWithPDN(w, game, reason)
+}
+
+// adminGamePDNHandler exports any finished game in PDN, for the support team, see requireAdmin.
+func adminGamePDNHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := mux.Vars(r)["id"]
+	game, reason, err := postgresClient.FetchGame(gameID)
+	if err != nil {
+		log.Printf("[%s] - (PDN) - Error fetching game %s: %v\n", name, gameID, err)
+		respondWithJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("Game not found: %s", gameID)})
+		return
+	}
+	respondWithPDN(w, game, reason)
+}
+
+func respondWithPDN(w http.ResponseWriter, game *models.Game, reason string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.pdn\"", game.ID))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(models.ExportPDN(*game, reason)))
+}
+
+// pdnImportHandler rebuilds a game from the PDN in the request body, checking every move. Players send
+// the token and sessionid of their game URL, the support team uses the admin route.
+func pdnImportHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPDNSize)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+		return
+	}
+	game, err := models.ImportPDN(string(body))
+	if err != nil {
+		respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	respondWithJSON(w, http.StatusOK, game)
+}
+
+// gameAnalysisHandler compares every turn of a finished game with the best move of the engine, for its
+// players. With ?turn=N only the position of that turn is analysed, deeper, with every legal move scored.
+func gameAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	game, _, ok := fetchPlayerGame(w, r)
+	if !ok {
+		return
+	}
+	// The whole game is analysed without ?turn=, turn is -1 then.
+	turn := -1
+	if turnParam := r.URL.Query().Get("turn"); turnParam != "" {
+		var err error
+		turn, err = strconv.Atoi(turnParam)
+		if err != nil || turn < 0 {
+			respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "turn must be a turn number, from 0"})
+			return
+		}
+	}
+	finished := !game.EndTime.IsZero()
+	if finished {
+		cached, err := redisClient.GetAnalysis(game.ID, turn)
+		if err != nil {
+			log.Printf("[%s] - (Game Analysis) - %v\n", name, err)
+		}
+		if cached != nil {
+			respondWithJSON(w, http.StatusOK, json.RawMessage(cached))
+			return
+		}
+	}
+
+	select {
+	case analyses <- struct{}{}:
+		defer func() { <-analyses }()
+	default:
+		w.Header().Set("Retry-After", "5")
+		respondWithJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Too many analyses running, try again later"})
+		return
+	}
+
+	turns, err := models.ReplayTurns(*game)
+	if err != nil {
+		log.Printf("[%s] - (Game Analysis) - Error replaying game %s: %v\n", name, game.ID, err)
+		respondWithJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	var analysis any
+	if turn >= 0 {
+		if turn >= len(turns) {
+			respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("turn must be between 0 and %d", len(turns)-1)})
+			return
+		}
+		turnAnalysis := engine.AnalyzePosition(turns[turn].Position, turns[turn].Side, engine.HintDepth, engine.HintMaxTime)
+		turnAnalysis.FEN = turns[turn].FEN
+		analysis = turnAnalysis
+	} else {
+		analysis = engine.AnalyzeTurns(turns, engine.AnalysisDepth, engine.AnalysisMaxTime)
+	}
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		log.Printf("[%s] - (Game Analysis) - JSON Marshal Error: %v\n", name, err)
+		respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if finished {
+		if err := redisClient.SaveAnalysis(game.ID, turn, data, analysisCacheTTL); err != nil {
+			log.Printf("[%s] - (Game Analysis) - %v\n", name, err)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, json.RawMessage(data))
+}
+
+// The dead letter routes are for the operators, see requireAdmin. The payloads are returned with the
+// player tokens and session IDs redacted.
+
+// deadLettersHandler lists the newest dead letters, ?queue= keeps the ones of a queue and ?count= sets
+// how many are returned, 50 by default.
+func deadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	count := 50
+	if countParam := r.URL.Query().Get("count"); countParam != "" {
+		var err error
+		count, err = strconv.Atoi(countParam)
+		if err != nil || count <= 0 {
+			respondWithJSON(w, http.StatusBadRequest, map[string]string{"error": "count must be a positive number"})
+			return
+		}
+	}
+	deadLetters, err := redisClient.GetDeadLetters(r.URL.Query().Get("queue"), count)
+	if err != nil {
+		log.Printf("[%s] - (Dead Letters) - Error fetching dead letters: %v\n", name, err)
+		respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	for i := range deadLetters {
+		deadLetters[i] = deadLetters[i].Redacted()
+	}
+	respondWithJSON(w, http.StatusOK, deadLetters)
+}
+
+// deadLetterHandler returns a single dead letter.
+func deadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	deadLetter, err := redisClient.GetDeadLetter(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithDeadLetterError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, deadLetter.Redacted())
+}
+
+// deadLetterReplayHandler pushes the dead letter back to its queue, to be handled again.
+func deadLetterReplayHandler(w http.ResponseWriter, r *http.Request) {
+	deadLetter, err := redisClient.ReplayDeadLetter(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithDeadLetterError(w, err)
+		return
+	}
+	log.Printf("[%s] - (Dead Letters) - Replayed %s to %s\n", name, deadLetter.ID, deadLetter.Queue)
+	respondWithJSON(w, http.StatusOK, deadLetter.Redacted())
+}
+
+// deadLetterDeleteHandler removes a single dead letter.
+func deadLetterDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := redisClient.DeleteDeadLetter(mux.Vars(r)["id"]); err != nil {
+		respondWithDeadLetterError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deadLettersPurgeHandler removes the dead letters of ?queue=, or all of them without it.
+func deadLettersPurgeHandler(w http.ResponseWriter, r *http.Request) {
+	queue := r.URL.Query().Get("queue")
+	purged, err := redisClient.PurgeDeadLetters(queue)
+	if err != nil {
+		log.Printf("[%s] - (Dead Letters) - Error purging dead letters: %v\n", name, err)
+		respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("[%s] - (Dead Letters) - Purged %d dead letters of queue %q\n", name, purged, queue)
+	respondWithJSON(w, http.StatusOK, map[string]int64{"purged": purged})
+}
+
+func respondWithDeadLetterError(w http.ResponseWriter, err error) {
+	if errors.Is(err, redisdb.ErrDeadLetterNotFound) {
+		respondWithJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("[%s] - (Dead Letters) - %v\n", name, err)
+	respondWithJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
 // Utility function to respond with JSON
 func respondWithJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -102,6 +332,16 @@ func respondWithJSON(w http.ResponseWriter, status int, payload interface{}) {
 
 func registerRoutes(r *mux.Router) {
 	r.HandleFunc("/api/gamelaunch", gameLaunchHandler).Methods("POST")
+	r.HandleFunc("/api/games/{id}/pdn", gamePDNHandler).Methods("GET")
+	r.HandleFunc("/api/games/{id}/analysis", gameAnalysisHandler).Methods("GET")
+	r.HandleFunc("/api/pdn/import", requireSession(pdnImportHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/games/{id}/pdn", requireAdmin(adminGamePDNHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/pdn/import", requireAdmin(pdnImportHandler)).Methods("POST")
+	r.HandleFunc("/api/deadletters", requireAdmin(deadLettersHandler)).Methods("GET")
+	r.HandleFunc("/api/deadletters", requireAdmin(deadLettersPurgeHandler)).Methods("DELETE")
+	r.HandleFunc("/api/deadletters/{id}", requireAdmin(deadLetterHandler)).Methods("GET")
+	r.HandleFunc("/api/deadletters/{id}", requireAdmin(deadLetterDeleteHandler)).Methods("DELETE")
+	r.HandleFunc("/api/deadletters/{id}/replay", requireAdmin(deadLetterReplayHandler)).Methods("POST")
 
 	healthHandler := func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -123,6 +363,25 @@ func main() {
 	port := config.FirstPortFromConfig(name)
 	addrs := fmt.Sprintf(":%d", port)
 
-	log.Printf("[API] - HTTP server starting on %d...", port)
-	log.Fatal(http.ListenAndServe(addrs, router))
+	// On SIGTERM the restapi stops accepting connections and finishes the requests in progress.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	server := &http.Server{Addr: addrs, Handler: router}
+	go func() {
+		log.Printf("[API] - HTTP server starting on %d...", port)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("[API] - Shutting down, finishing the requests in progress...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[API] - Error shutting down the http server: %v\n", err)
+	}
+	log.Println("[API] - Shut down")
 }
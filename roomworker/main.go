@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/Lavizord/checkers-server/config"
+	"github.com/Lavizord/checkers-server/engine"
 	"github.com/Lavizord/checkers-server/interfaces"
 	"github.com/Lavizord/checkers-server/messages"
 	"github.com/Lavizord/checkers-server/models"
@@ -20,6 +25,7 @@ var pid int
 var redisClient *redisdb.RedisClient
 var postgresClient *postgrescli.PostgresCli
 var name = "roomworker"
+var consumer = redisdb.ConsumerName()
 
 func init() {
 	pid = os.Getpid()
@@ -54,153 +60,241 @@ func main() {
 		}
 	}()
 
-	go processReadyQueue()
-	go processRoomEnding()
-	go processQueue()
-	select {}
+	// On SIGTERM the roomworker stops reading the queues and finishes the messages and pairings it is handling.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	var workers sync.WaitGroup
+	for _, process := range []func(context.Context){processReadyQueue, processRoomEnding, processQueue} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			process(ctx)
+		}()
+	}
+	<-ctx.Done()
+	log.Printf("[RoomWorker-%d] - Shutting down, finishing the messages being handled...\n", pid)
+	workers.Wait()
+	log.Printf("[RoomWorker-%d] - Shut down\n", pid)
 }
 
-func processRoomCreation() {
-	for {
-		playerData, err := redisClient.BLPop("create_room", 0) // Block
-		if err != nil {
-			log.Printf("[RoomWorker-%d] - Error retrieving player:%v\n", pid, err)
-			continue
-		}
+func processRoomCreation(ctx context.Context) {
+	redisClient.ConsumePlayers(ctx, "create_room", name, consumer, func(playerData *models.Player) error {
 		//log.Printf("[RoomWorker-%d] - create room!: %+v\n", pid, playerData)
 		handleCreateRoom(playerData)
-	}
+		return nil
+	})
 }
 
-func processRoomJoin() {
-	for {
-		playerData, err := redisClient.BLPop("join_room", 0) // Block
-		if err != nil {
-			log.Printf("[RoomWorker-%d] - Error retrieving player:%v\n", pid, err)
-			continue
-		}
+func processRoomJoin(ctx context.Context) {
+	redisClient.ConsumePlayers(ctx, "join_room", name, consumer, func(playerData *models.Player) error {
 		//log.Printf("[RoomWorker-%d] - processing join room!: %+v\n", pid, playerData)
 		handleJoinRoom(playerData)
-	}
+		return nil
+	})
 }
 
-func processQueue() {
+func processQueue(ctx context.Context) {
 	// Launch a goroutine for each bet queue
+	var queues sync.WaitGroup
+	startQueue := func(bet float64) {
+		queues.Add(1)
+		go func() {
+			defer queues.Done()
+			processQueueForBet(ctx, bet)
+		}()
+	}
 	for _, bet := range models.DamasValidBetAmounts {
-		go processQueueForBet(bet)
+		startQueue(bet)
 	}
-
-	// Block forever or wait on a channel (to prevent the main goroutine from exiting)
-	select {}
+	startQueue(models.PracticeBetValue)
+	queues.Wait()
 }
 
-func processQueueForBet(bet float64) {
-	queueName := fmt.Sprintf("queue:%f", bet)
-	for {
-		// Block indefinitely for player1 (this goroutine is dedicated to this queue)
-		player1, err := redisClient.BLPop(queueName, 0)
-		if err != nil {
-			log.Printf("[RoomWorker-%d] - Error retrieving player 1 from %s: %v\n", pid, queueName, err)
+func processQueueForBet(ctx context.Context, bet float64) {
+	// The bet queues are streams read in the roomworker group. The players being paired stay pending
+	// until they are, so the ones of a roomworker that stops are claimed by the others, and leave the
+	// stream once paired or dropped. A player left alone is pushed back to the end of the queue.
+	queue := redisClient.NewStreamConsumer(fmt.Sprintf("queue:%f", bet), name, consumer)
+	queue.DeleteAcked = true
+	for ctx.Err() == nil {
+		err := queue.CreateGroup()
+		if err == nil {
+			break
+		}
+		log.Printf("[RoomWorker-%d] - %v\n", pid, err)
+		time.Sleep(time.Second)
+	}
+	// The player alone in the queue keeps being re-queued, we track since when to pair it with a bot.
+	waitingID, waitingSince := "", time.Now()
+	for ctx.Err() == nil {
+		// Wait for player1 (this goroutine is dedicated to this queue), a second at a time to see the shutdown.
+		message1, player1 := nextQueuedPlayer(queue, time.Second)
+		if player1 == nil {
 			continue
 		}
-		//log.Printf("[RoomWorker-%d] - Retrieved player 1 from %s: %v\n", pid, queueName, player1)
+		//log.Printf("[RoomWorker-%d] - Retrieved player 1 from %s: %v\n", pid, queue.Queue, player1)
 		player1Details, err := redisClient.GetPlayer(player1.ID)
 		if err != nil {
 			log.Printf("[RoomWorker-%d] - Error retrieving player 1 details, player removed from queue: %v\n", pid, err)
+			dropQueuedPlayer(queue, message1)
 			redisClient.DecrementQueueCount(bet)
 			continue
 		}
 		// we check to see if the player is eligible to be processed.
 		if !player1Details.IsEligibleForQueue(bet) {
-			log.Printf("[RoomWorker-%d] - player1 not eligible to be processed by the queue, player removed from queue: %v\n", pid, queueName)
+			log.Printf("[RoomWorker-%d] - player1 not eligible to be processed by the queue, player removed from queue: %v\n", pid, queue.Queue)
+			dropQueuedPlayer(queue, message1)
 			redisClient.DecrementQueueCount(bet)
 			continue
 		}
+		// Players that asked for a bot don't wait for an opponent, queue_bot is only taken for practice games.
+		if player1Details.BotDifficulty != "" && bet == models.PracticeBetValue {
+			pairQueuedWithBot(queue, message1, player1, player1Details.BotDifficulty)
+			continue
+		}
+		if player1.ID != waitingID {
+			waitingID, waitingSince = player1.ID, time.Now()
+		}
 		// Try fetching the second player with a timeout
-		player2, err := redisClient.BLPop(queueName, config.Cfg.Services["roomworker"].Timer)
-		if err != nil {
-			log.Printf("[RoomWorker-%d] - No second player found in %s, re-queueing player 1.\n", pid, queueName)
+		message2, player2 := nextQueuedPlayer(queue, time.Duration(config.Cfg.Services["roomworker"].Timer)*time.Second)
+		if player2 == nil {
+			botWait := config.Cfg.Services["roomworker"].BotWait
+			difficulty := waitBotDifficulty(bet)
+			if botWait > 0 && difficulty != "" && time.Since(waitingSince) >= time.Duration(botWait)*time.Second {
+				log.Printf("[RoomWorker-%d] - No second player found in %s for %ds, pairing player 1 with a bot.\n", pid, queue.Queue, botWait)
+				waitingID = ""
+				pairQueuedWithBot(queue, message1, player1, difficulty)
+				continue
+			}
+			log.Printf("[RoomWorker-%d] - No second player found in %s, re-queueing player 1.\n", pid, queue.Queue)
 			// Since we failed to get the player2, we will requeue the player1.
 			time.Sleep(time.Second * 1)
-			redisClient.RPush(queueName, player1)
+			requeuePlayer(queue, message1)
 			continue
 		}
-		//log.Printf("[RoomWorker-%d] - Retrieved player 2 from %s: %v\n", pid, queueName, player2)
+		//log.Printf("[RoomWorker-%d] - Retrieved player 2 from %s: %v\n", pid, queue.Queue, player2)
 		player2Details, err := redisClient.GetPlayer(player2.ID)
 		if err != nil {
 			log.Printf("[RoomWorker-%d] - Error retrieving player 2 details: %v\n", pid, err)
-			redisClient.RPush(queueName, player1)
+			requeuePlayer(queue, message1)
+			dropQueuedPlayer(queue, message2)
 			redisClient.DecrementQueueCount(bet)
 			continue
 		}
 		if player1Details.ID == player2Details.ID {
-			log.Printf("[RoomWorker-%d] - player1Details.ID == player2Details.ID, player2 removed from queue: %v\n", pid, queueName)
-			redisClient.RPush(queueName, player1)
+			log.Printf("[RoomWorker-%d] - player1Details.ID == player2Details.ID, player2 removed from queue: %v\n", pid, queue.Queue)
+			requeuePlayer(queue, message1)
+			dropQueuedPlayer(queue, message2)
 			redisClient.DecrementQueueCount(bet)
 			continue
 		}
 		// before we handle the paired, we will do a final check to make sure the players2 is still online / valid.
 		if !player2Details.IsEligibleForQueue(bet) {
 			// If it is not valid, we will add player 1 back to the queue.
-			log.Printf("[RoomWorker-%d] - player2 not eligible to be processed by the queue, player removed from queue: %v\n", pid, queueName)
-			redisClient.RPush(queueName, player1)
+			log.Printf("[RoomWorker-%d] - player2 not eligible to be processed by the queue, player removed from queue: %v\n", pid, queue.Queue)
+			requeuePlayer(queue, message1)
+			dropQueuedPlayer(queue, message2)
 			redisClient.DecrementQueueCount(bet)
 			continue
 		}
-		// Process both players
-		//log.Printf("[RoomWorker-%d] - Pairing players: %s and %s from %s\n", pid, player1, player2, queueName)
+		if player2Details.BotDifficulty != "" && bet == models.PracticeBetValue {
+			requeuePlayer(queue, message1)
+			pairQueuedWithBot(queue, message2, player2, player2Details.BotDifficulty)
+			continue
+		}
+		waitingID = ""
+		// Process both players, they leave the queue once paired.
+		//log.Printf("[RoomWorker-%d] - Pairing players: %s and %s from %s\n", pid, player1, player2, queue.Queue)
 		handleQueuePaired(player1, player2)
+		dropQueuedPlayer(queue, message1)
+		dropQueuedPlayer(queue, message2)
 	}
 }
 
-func processReadyQueue() {
-	for {
-		playerData, err := redisClient.BLPop("ready_queue", 0) // Block
-		if err != nil {
-			log.Printf("[RoomWorker-%d] - Error retrieving player:%v\n", pid, err)
-			continue
-		}
+// nextQueuedPlayer waits up to block for the next player of the bet queue, nil when there was none.
+// Messages that are not players are dead lettered.
+func nextQueuedPlayer(queue *redisdb.StreamConsumer, block time.Duration) (redisdb.StreamMessage, *models.Player) {
+	message, err := queue.Receive(block)
+	if err != nil {
+		log.Printf("[RoomWorker-%d] - Error reading %s: %v\n", pid, queue.Queue, err)
+		time.Sleep(time.Second)
+		return redisdb.StreamMessage{}, nil
+	}
+	if message == nil || !queue.Accept(*message) {
+		return redisdb.StreamMessage{}, nil
+	}
+	var player models.Player
+	if err := json.Unmarshal([]byte(message.Payload), &player); err != nil {
+		queue.Finish(*message, fmt.Errorf("failed to deserialize player: %w", err))
+		return redisdb.StreamMessage{}, nil
+	}
+	return *message, &player
+}
+
+// dropQueuedPlayer takes the player out of the bet queue, once paired or when it cannot be.
+func dropQueuedPlayer(queue *redisdb.StreamConsumer, message redisdb.StreamMessage) {
+	if err := queue.Ack(message.ID); err != nil {
+		log.Printf("[RoomWorker-%d] - Error removing player from %s: %v\n", pid, queue.Queue, err)
+	}
+}
+
+// requeuePlayer pushes the player back to the end of the bet queue, to wait for another opponent.
+func requeuePlayer(queue *redisdb.StreamConsumer, message redisdb.StreamMessage) {
+	if err := queue.Requeue(message); err != nil {
+		log.Printf("[RoomWorker-%d] - Error re-queueing player in %s: %v\n", pid, queue.Queue, err)
+	}
+}
+
+// pairQueuedWithBot pairs the player of the bet queue with a bot, it is re-queued when the bot could
+// not be added.
+func pairQueuedWithBot(queue *redisdb.StreamConsumer, message redisdb.StreamMessage, player *models.Player, difficulty string) {
+	if pairWithBot(player, difficulty) {
+		dropQueuedPlayer(queue, message)
+	} else {
+		requeuePlayer(queue, message)
+	}
+}
+
+func processReadyQueue(ctx context.Context) {
+	redisClient.ConsumePlayers(ctx, "ready_queue", name, consumer, func(playerData *models.Player) error {
 		log.Printf("[RoomWorker-%d] - processing ready room!: %+v\n", pid, playerData)
 		// Aqui ou damos handle do ready queue ou handle do unreadyqueue
 		if playerData.Status == models.StatusInRoomReady {
 			handleReadyRoom(playerData)
-			continue
+			return nil
 		}
 		if playerData.Status == models.StatusInRoom {
 			handleUnReadyRoom(playerData)
-			continue
+			return nil
 		}
 		log.Printf("Player is neither InRoomReady neither InRoom?!")
-	}
+		return fmt.Errorf("player is neither InRoomReady neither InRoom: %v", playerData.Status)
+	})
 }
 
-func processRoomEnding() {
-	for {
-		playerWhoLeft, err := redisClient.BLPop("leave_room", 0)
-		if err != nil {
-			log.Printf("[RoomWorker-%d] - processRoomEnding - Error retrieving player:%v\n", pid, err)
-			continue
-		}
+func processRoomEnding(ctx context.Context) {
+	redisClient.ConsumePlayers(ctx, "leave_room", name, consumer, func(playerWhoLeft *models.Player) error {
 		//log.Printf("[RoomWorker-%d] - Processing the end of room: %+v\n", pid, playerWhoLeft)
 		room, err := redisClient.GetRoomByID(playerWhoLeft.RoomID)
 		if err != nil {
 			log.Printf("[RoomWorker-%d] - processRoomEnding - Error retrieving room:%v\n", pid, err)
-			continue
+			return err
 		}
 		player2ID, err := room.GetOpponentPlayerID(playerWhoLeft.ID)
 		if err != nil {
 			log.Printf("[RoomWorker-%d] - processRoomEnding - Error retrieving opponent id:%v\n", pid, err)
-			continue
+			return err
 		}
 		player2, err := redisClient.GetPlayer(player2ID)
 		if err != nil {
 			log.Printf("[RoomWorker-%d] - processRoomEnding - Error retrieving opponent player:%v\n", pid, err)
-			continue
+			return err
 		}
 		msg, err := messages.NewMessage("opponent_left_room", true)
 		if err != nil {
 			log.Printf("[RoomWorker-%d] - processRoomEnding - Error generating message:%v\n", pid, err)
-			continue
+			return err
 		}
 		redisClient.PublishToPlayer(*player2, string(msg))
 		addPlayerToQueue(player2, true, true)
@@ -209,11 +303,47 @@ func processRoomEnding() {
 		err = redisClient.RemoveRoom(redisdb.GenerateRoomRedisKeyById(room.ID))
 		if err != nil {
 			log.Printf("[RoomWorker-%d] - processRoomEnding - Error removing room: %v\n", pid, err)
-			continue
+			return err
 		}
 		// redisClient.DecrementQueueCount(playerWhoLeft.SelectedBet) 		// we dont need to decrement it here, since the queue decrements
 		// log.Printf("[RoomWorker-%d] - End of room ending: %v\n", pid, err)
+		return nil
+	})
+}
+
+// waitBotDifficulty is the difficulty of the bot a player that waited bot_wait in the queue is paired
+// with, the roomworker bot_difficulty. Empty when there is none, bots only play practice games.
+func waitBotDifficulty(bet float64) string {
+	if bet != models.PracticeBetValue {
+		return ""
+	}
+	difficulty := config.Cfg.Services["roomworker"].BotDifficulty
+	if difficulty == "" {
+		difficulty = engine.DefaultDifficulty
+	}
+	if !engine.IsValidDifficulty(difficulty) {
+		return ""
 	}
+	return difficulty
+}
+
+// pairWithBot pairs the player with a new bot, it returns false when the bot could not be added and
+// the player has to be re-queued. The bot is ready from the start, the game starts as soon as the
+// player is ready.
+func pairWithBot(player *models.Player, difficulty string) bool {
+	bot := models.NewBotPlayer(player, difficulty)
+	if err := redisClient.AddPlayer(bot); err != nil {
+		log.Printf("[RoomWorker-%d] - Error adding bot player, re-queueing player: %v\n", pid, err)
+		return false
+	}
+	// The botworker subscribes to the bot channel and plays its games.
+	if err := redisClient.StreamPush("bot_players", bot); err != nil {
+		log.Printf("[RoomWorker-%d] - Error handing the bot to the botworker, re-queueing player: %v\n", pid, err)
+		redisClient.RemovePlayer(bot.ID)
+		return false
+	}
+	handleQueuePaired(player, bot)
+	return true
 }
 
 func handleQueuePaired(player1, player2 *models.Player) {
@@ -225,6 +355,7 @@ func handleQueuePaired(player1, player2 *models.Player) {
 		Currency:           player1.Currency,
 		BetValue:           player1.SelectedBet,
 		OperatorIdentifier: player1.OperatorIdentifier,
+		IsPractice:         player1.SelectedBet == models.PracticeBetValue,
 	}
 
 	player1.RoomID = room.ID
@@ -244,11 +375,13 @@ func handleQueuePaired(player1, player2 *models.Player) {
 			redisClient.UpdatePlayer(player1)
 			redisClient.UpdatePlayer(player2)
 			redisClient.RemoveRoom(redisdb.GenerateRoomRedisKeyById(room.ID))
-			redisClient.DecrementQueueCount(player1.SelectedBet)
-			redisClient.DecrementQueueCount(player2.SelectedBet)
+			decrementQueueCount(player1)
+			decrementQueueCount(player2)
 			msg, _ := messages.GenerateGenericMessage("error", "failed to handle queue paired.")
 			redisClient.PublishToPlayer(*player1, string(msg))
 			redisClient.PublishToPlayer(*player2, string(msg))
+			releaseBot(player1)
+			releaseBot(player2)
 		}
 	}()
 
@@ -292,8 +425,38 @@ func handleQueuePaired(player1, player2 *models.Player) {
 	}
 
 	cleanup = false
-	redisClient.DecrementQueueCount(player1.SelectedBet)
-	redisClient.DecrementQueueCount(player2.SelectedBet)
+	decrementQueueCount(player1)
+	decrementQueueCount(player2)
+	setBotReady(player1, player2)
+	setBotReady(player2, player1)
+}
+
+// decrementQueueCount removes the player from the queue count, bots are never counted.
+func decrementQueueCount(player *models.Player) {
+	if player.IsBot {
+		return
+	}
+	redisClient.DecrementQueueCount(player.SelectedBet)
+}
+
+// setBotReady readies the bot as soon as it is paired and lets the opponent know.
+func setBotReady(bot, opponent *models.Player) {
+	if !bot.IsBot {
+		return
+	}
+	bot.Status = models.StatusInRoomReady
+	redisClient.UpdatePlayer(bot)
+	msg, _ := messages.GenerateOpponentReadyMessage(true)
+	redisClient.PublishPlayerEvent(opponent, string(msg))
+}
+
+// releaseBot tells the botworker the bot is no longer needed, it removes the bot player.
+func releaseBot(bot *models.Player) {
+	if !bot.IsBot {
+		return
+	}
+	msg, _ := messages.NewMessage("opponent_left_room", true)
+	redisClient.PublishPlayerEvent(bot, string(msg))
 }
 
 func handleReadyRoom(player *models.Player) {
@@ -343,22 +506,36 @@ func handleReadyRoom(player *models.Player) {
 		redisClient.PublishPlayerEvent(player, string(msg))
 		return
 	}
-	// Now! If both players are ready...!!
+	// Now! If both players are ready...!! Practice rooms are free, there is nothing to bet.
+	if !proom.IsPractice && !postRoomBets(player, player2, proom) {
+		return
+	}
+	// Then we start a match
+	roomdata, err := json.Marshal(proom)
+	err = redisClient.StreamPushGeneric("create_game", roomdata)
+	if err != nil {
+		log.Printf("[RoomWorker-%d] - Error handleReadyRoom Creating Game StreamPushGeneric:%s\n", pid, err)
+	}
+}
+
+// postRoomBets posts the bet of both players to the operator wallet before the game starts. When a bet
+// fails the room is closed, and false is returned.
+func postRoomBets(player, player2 *models.Player, proom *models.Room) bool {
 	// Before we start the game, we will need to post to the wallet api of the bet, we will use our api interface for that.
 	module, exists := interfaces.OperatorModules[proom.OperatorIdentifier.OperatorName]
 	if !exists {
-		log.Printf("[RoomWorker-%d] - Error handleReadyRoom getting getting interfaces.OperatorModules[%v]:%s\n", pid, proom.OperatorIdentifier.OperatorName, err)
-		return
+		log.Printf("[RoomWorker-%d] - Error postRoomBets getting interfaces.OperatorModules[%v]\n", pid, proom.OperatorIdentifier.OperatorName)
+		return false
 	}
 	session1, err := redisClient.GetSessionByID(player.SessionID)
 	if err != nil {
-		log.Printf("[RoomWorker-%d] - Error handleReadyRoom fetching player1 sessionID:%s\n", pid, err)
-		return
+		log.Printf("[RoomWorker-%d] - Error postRoomBets fetching player1 sessionID:%s\n", pid, err)
+		return false
 	}
 	session2, err := redisClient.GetSessionByID(player2.SessionID)
 	if err != nil {
-		log.Printf("[RoomWorker-%d] - Error handleReadyRoom fetching player2 sessionID:%s\n", pid, err)
-		return
+		log.Printf("[RoomWorker-%d] - Error postRoomBets fetching player2 sessionID:%s\n", pid, err)
+		return false
 	}
 
 	newBalance1, err := module.HandlePostBet(postgresClient, redisClient, *session1, int64(proom.BetValue*100), proom.ID)
@@ -376,7 +553,7 @@ func handleReadyRoom(player *models.Player) {
 		// since the first player failed the api check, we will queue up the second plyer.
 		addPlayerToQueue(player2, true, true)
 		// TODO: CREDITAR VALOR A JOGADOR.
-		return
+		return false
 	}
 	newBalance2, err := module.HandlePostBet(postgresClient, redisClient, *session2, int64(proom.BetValue*100), proom.ID)
 	if err != nil {
@@ -390,7 +567,7 @@ func handleReadyRoom(player *models.Player) {
 		// since the second player failed the api check, we will queue up the first player.
 		addPlayerToQueue(player2, true, true)
 		// TODO: CREDITAR VALOR A JOGADOR.
-		return
+		return false
 	}
 	// Now that everything is OK, we will start up the game
 	msgP1, _ := messages.NewMessage("balance_update", float64(newBalance1)/100)
@@ -402,13 +579,7 @@ func handleReadyRoom(player *models.Player) {
 
 	redisClient.PublishPlayerEvent(player, string(msgP1))
 	redisClient.PublishPlayerEvent(player2, string(msgP2))
-
-	// Then we start a match
-	roomdata, err := json.Marshal(proom)
-	err = redisClient.RPushGeneric("create_game", roomdata)
-	if err != nil {
-		log.Printf("[RoomWorker-%d] - Error handleReadyRoom Creating Game RPushGeneric:%s\n", pid, err)
-	}
+	return true
 }
 
 func handleUnReadyRoom(player *models.Player) {
@@ -530,15 +701,19 @@ func handleCreateRoom(player *models.Player) {
 }
 
 func addPlayerToQueue(player *models.Player, incrementQueueCount, notify bool) {
+	// Bots only play the player they were paired with, they leave on opponent_left_room.
+	if player.IsBot {
+		return
+	}
 	// Reset both player data.
 	player.RoomID = ""
 	player.GameID = ""
 	player.Status = models.StatusInQueue
 	redisClient.UpdatePlayer(player)
 
-	// Pushing the player to the "queue" Redis list
+	// Pushing the player to the "queue" Redis stream
 	queueName := fmt.Sprintf("queue:%f", player.SelectedBet)
-	err := redisClient.RPush(queueName, player)
+	err := redisClient.StreamPush(queueName, player)
 	if err != nil {
 		log.Printf("[RoomWorker-%d] - Error adding player to queue:%v\n", pid, err)
 		return
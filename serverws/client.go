@@ -140,6 +140,7 @@ func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		log.Println("[Client] - writePump defer")
+		c.hub.writers.Done()
 		c.cancel()
 		c.hub.unregister <- c
 		ticker.Stop()
@@ -257,6 +258,7 @@ func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
+	hub.writers.Add(1)
 	go client.writePump()
 	go client.readPump()
 
@@ -270,7 +272,7 @@ func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 
 	// Now that our player has subscribbed to our stuff, we will notify the gameworker of the reconnect.
 	if wasdisconnected {
-		hub.redis.RPush("reconnect_game", player)
+		hub.redis.SendGameCommand(models.GameCommandReconnect, player.GameID, player)
 	}
 
 }
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"log"
+	"sync"
 
+	"github.com/Lavizord/checkers-server/messages"
 	"github.com/Lavizord/checkers-server/models"
 	"github.com/Lavizord/checkers-server/redisdb"
 	"github.com/redis/go-redis/v9"
@@ -24,6 +26,12 @@ type Hub struct {
 	// Unregister requests from clients.
 	unregister chan *Client
 
+	// Drain requests, closed once every client was told to reconnect.
+	drain chan chan struct{}
+
+	// Write pumps running, each one ends once its client messages are sent.
+	writers sync.WaitGroup
+
 	redis *redisdb.RedisClient
 
 	broadastpubsub *redis.PubSub
@@ -41,6 +49,7 @@ func newHub(addr string, username string, password string) *Hub {
 		broadcast:  make(chan []byte),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		drain:      make(chan chan struct{}),
 		clients:    make(map[*Client]bool),
 		redis:      redisclient,
 	}
@@ -62,20 +71,23 @@ func (h *Hub) run() {
 		case client := <-h.unregister:
 			log.Println("[HUB.Run] - Unregister")
 			if _, ok := h.clients[client]; ok {
-				client.UpdatePlayerDataFromRedis()
-				h.redis.UpdatePlayersInQueueSet(client.player.ID, models.StatusOffline)
-				if client.player.RoomID != "" || client.player.Status == models.StatusInRoom || client.player.Status == models.StatusInRoomReady {
-					log.Printf("[Hub.Run] - Removed player is in a Room, sending notification to room worker!: %v\n", client.player)
-					h.redis.RPush("leave_room", client.player)
-				}
-				if client.player.GameID != "" || client.player.Status == models.StatusInGame {
-					log.Printf("[Hub.Run] - Removed player is in a Game, sending notification to Game worker!: %v\n", client.player)
-					h.redis.RPush("disconnect_game", client.player)
+				h.removeClient(client)
+			}
+
+		case done := <-h.drain:
+			log.Printf("[HUB.Run] - Draining %d clients\n", len(h.clients))
+			msg, _ := messages.NewMessage("server_shutdown", "reconnect")
+			for client := range h.clients {
+				select {
+				case client.send <- msg:
+				default:
 				}
-				h.redis.RemovePlayer(client.player.ID)
-				delete(h.clients, client)
-				close(client.send)
+				// Closing send makes the write pump close the connection once the message is sent, the
+				// player channel subscription is stopped first as it also sends to the client.
+				client.cancel()
+				h.removeClient(client)
 			}
+			close(done)
 
 		case message := <-h.broadcast:
 			//msg, _ := json.Marshal(message)
@@ -85,24 +97,48 @@ func (h *Hub) run() {
 				select {
 				case client.send <- message:
 				default:
-					h.redis.UpdatePlayersInQueueSet(client.player.ID, models.StatusOffline)
-					if client.player.RoomID != "" || client.player.Status == models.StatusInRoom || client.player.Status == models.StatusInRoomReady {
-						log.Printf("[Hub.Run] - Removed player is in a Room, sending notification to room worker!: %v\n", client.player)
-						h.redis.RPush("leave_room", client.player)
-					}
-					if client.player.GameID != "" || client.player.Status == models.StatusInGame {
-						log.Printf("[Hub.Run] - Removed player is in a Game, sending notification to Game worker!: %v\n", client.player)
-						h.redis.RPush("disconnect_game", client.player)
-					}
-					h.redis.RemovePlayer(client.player.ID)
-					close(client.send)
-					delete(h.clients, client)
+					h.removeClient(client)
 				}
 			}
 		}
 	}
 }
 
+// removeClient tells the workers the player of the client is gone, like a disconnect, and closes
+// the client send channel.
+func (h *Hub) removeClient(client *Client) {
+	client.UpdatePlayerDataFromRedis()
+	h.redis.UpdatePlayersInQueueSet(client.player.ID, models.StatusOffline)
+	if client.player.RoomID != "" || client.player.Status == models.StatusInRoom || client.player.Status == models.StatusInRoomReady {
+		log.Printf("[Hub.Run] - Removed player is in a Room, sending notification to room worker!: %v\n", client.player)
+		h.redis.StreamPush("leave_room", client.player)
+	}
+	if client.player.GameID != "" || client.player.Status == models.StatusInGame {
+		log.Printf("[Hub.Run] - Removed player is in a Game, sending notification to Game worker!: %v\n", client.player)
+		h.redis.SendGameCommand(models.GameCommandDisconnect, client.player.GameID, client.player)
+	}
+	h.redis.RemovePlayer(client.player.ID)
+	delete(h.clients, client)
+	close(client.send)
+}
+
+// Drain sends server_shutdown to every client and closes their connections, new clients are no
+// longer accepted by then. It returns once the messages are sent, or once ctx is done.
+func (h *Hub) Drain(ctx context.Context) {
+	done := make(chan struct{})
+	h.drain <- done
+	<-done
+	sent := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-ctx.Done():
+	}
+}
+
 func (h *Hub) SubscribeBroadcast() {
 	pubsub := h.redis.Client.Subscribe(context.Background(), "game_info")
 	h.broadastpubsub = pubsub
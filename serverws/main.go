@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Lavizord/checkers-server/config"
 )
@@ -31,8 +36,26 @@ func main() {
 		serveWs(hub, w, r)
 	})
 
-	err := http.ListenAndServe(*addr, nil)
-	if err != nil {
-		log.Fatal("ListenAndServe: ", err)
+	// On SIGTERM the wsapi stops accepting connections and tells its clients to reconnect, the load
+	// balancer sends them to the other instances.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	server := &http.Server{Addr: *addr}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("ListenAndServe: ", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("[WSAPI] - Shutting down, draining the clients...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*writeWait)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[WSAPI] - Error shutting down the http server: %v\n", err)
 	}
+	hub.Drain(shutdownCtx)
+	log.Println("[WSAPI] - Shut down")
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/Lavizord/checkers-server/messages"
 
@@ -17,7 +18,7 @@ import (
 // might send the message to redis.
 func RouteMessages(message *messages.Message[json.RawMessage], client *Client, redis *redisdb.RedisClient) {
 	switch message.Command {
-	case "queue":
+	case "queue", "queue_bot":
 		handleQueue(message, client, redis)
 		return
 
@@ -55,6 +56,24 @@ func RouteMessages(message *messages.Message[json.RawMessage], client *Client, r
 		}
 		handleMovePiece(message, client, redis)
 		return
+
+	case "offer_draw", "accept_draw", "decline_draw":
+		if client.player.Status != models.StatusInGame {
+			msg, _ := messages.GenerateGenericMessage("invalid", "Can't issue a draw command when not in a Game.")
+			client.send <- msg
+			return
+		}
+		handleDrawCommand(message, client, redis)
+		return
+
+	case "request_hint":
+		if client.player.Status != models.StatusInGame {
+			msg, _ := messages.GenerateGenericMessage("invalid", "Can't ask for a hint when not in a Game.")
+			client.send <- msg
+			return
+		}
+		handleRequestHint(client, redis)
+		return
 	}
 }
 
@@ -120,7 +139,7 @@ func handleReadyQueue(msg *messages.Message[json.RawMessage], client *Client, re
 		client.send <- msgBytes
 		return
 	}
-	err = redis.RPush("ready_queue", client.player) // now we tell roomworker to process this player ready.
+	err = redis.StreamPush("ready_queue", client.player) // now we tell roomworker to process this player ready.
 	if err != nil {
 		log.Printf("Error pushing player to Redis ready queue: %v\n", err)
 		msgBytes, _ := messages.GenerateGenericMessage("error", "Pushing player to queue: "+err.Error())
@@ -146,7 +165,7 @@ func handleLeaveRoom(client *Client, redis *redisdb.RedisClient) {
 		client.send <- msg
 		return
 	}
-	err = redis.RPush("leave_room", client.player)
+	err = redis.StreamPush("leave_room", client.player)
 	if err != nil {
 		log.Printf("Error pushing player to Redis leave_room queue: %v\n", err)
 		msg, _ := messages.GenerateGenericMessage("error", "error leaving room.")
@@ -158,9 +177,12 @@ func handleLeaveRoom(client *Client, redis *redisdb.RedisClient) {
 func handleLeaveGame(client *Client, redis *redisdb.RedisClient) {
 	msgBytes, _ := messages.GenerateGenericMessage("invalid", "Processing 'leave_game'")
 	client.send <- msgBytes
-	err := redis.RPush("leave_game", client.player)
+	player, err := redis.GetPlayer(client.player.ID)
+	if err == nil {
+		err = redis.SendGameCommand(models.GameCommandLeave, player.GameID, player)
+	}
 	if err != nil {
-		log.Printf("Error pushing player to Redis leave_game queue: %v\n", err)
+		log.Printf("Error sending leave_game to the game mailbox: %v\n", err)
 		msgBytes, _ := messages.GenerateGenericMessage("error", "Error adding player to leave_game")
 		client.send <- msgBytes
 		return
@@ -182,12 +204,46 @@ func handleMovePiece(message *messages.Message[json.RawMessage], client *Client,
 		client.send <- msg
 		return
 	}
-	// movement message is sent to the game worker
-	err = redis.RPushGeneric("move_piece", message.Value)
+	// The receive time is set here, the game worker times the move from it.
+	move.ReceivedAt = time.Now()
+	// movement message is sent to the mailbox of the player game, the game worker runs it in order.
+	player, err := redis.GetPlayer(client.player.ID)
+	if err == nil {
+		err = redis.SendGameCommand(models.GameCommandMove, player.GameID, move)
+	}
 	if err != nil {
-		log.Printf("Error pushing move to Redis handleMovePiece queue: %v\n", err)
+		log.Printf("Error sending move to the game mailbox: %v\n", err)
 		msg, _ := messages.GenerateGenericMessage("error", "error pushing move to gameworker.")
 		client.send <- msg
 		return
 	}
 }
+
+func handleDrawCommand(message *messages.Message[json.RawMessage], client *Client, redis *redisdb.RedisClient) {
+	drawCommand := models.DrawCommand{
+		PlayerID: client.player.ID,
+		Command:  message.Command,
+	}
+	// draw commands are sent to the mailbox of the player game
+	player, err := redis.GetPlayer(client.player.ID)
+	if err == nil {
+		err = redis.SendGameCommand(models.GameCommandDraw, player.GameID, drawCommand)
+	}
+	if err != nil {
+		log.Printf("Error sending draw command to the game mailbox: %v\n", err)
+		msg, _ := messages.GenerateGenericMessage("error", "error pushing draw command to gameworker.")
+		client.send <- msg
+		return
+	}
+}
+
+// handleRequestHint sends the hint request to the gameworker, it checks the game is a practice game.
+func handleRequestHint(client *Client, redis *redisdb.RedisClient) {
+	err := redis.StreamPush("request_hint", client.player)
+	if err != nil {
+		log.Printf("Error pushing player to Redis request_hint queue: %v\n", err)
+		msgBytes, _ := messages.GenerateGenericMessage("error", "Error asking for a hint")
+		client.send <- msgBytes
+		return
+	}
+}
@@ -1,11 +1,11 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 
+	"github.com/Lavizord/checkers-server/engine"
 	"github.com/Lavizord/checkers-server/messages"
 	"github.com/Lavizord/checkers-server/models"
 	"github.com/Lavizord/checkers-server/redisdb"
@@ -16,6 +16,8 @@ type QueueHandler struct {
 	RedisClient *redisdb.RedisClient
 	Msg         *messages.Message[json.RawMessage]
 
+	botDifficulty string // Set for queue_bot, the player is paired with a bot.
+
 	// Track changes for cleanup
 	initialValidationsFailed bool
 	statusUpdated            bool
@@ -47,14 +49,7 @@ func (qh *QueueHandler) Process() {
 		return
 	}
 
-	found := false
-	for _, v := range models.DamasValidBetAmounts {
-		if v == betValue {
-			found = true
-			break
-		}
-	}
-	if !found {
+	if !models.IsValidBet(betValue) {
 		qh.initialValidationsFailed = true
 		log.Print("Bet is not valid for the configured ValidBetAmounts")
 		return
@@ -83,7 +78,7 @@ func (qh *QueueHandler) cleanup() {
 
 	if qh.addedToQueue {
 		queueName := fmt.Sprintf("queue:%f", qh.Client.player.SelectedBet)
-		qh.RedisClient.Client.LRem(context.Background(), queueName, 1, qh.Client.player)
+		qh.RedisClient.RemovePlayerFromQueue(queueName, qh.Client.player)
 		sendFailedQueueConfirmation = true
 	}
 
@@ -109,6 +104,9 @@ func (qh *QueueHandler) validateStatusTransition() bool {
 }
 
 func (qh *QueueHandler) parseBetValue() (float64, error) {
+	if qh.Msg.Command == "queue_bot" {
+		return qh.parseBotQueueValue()
+	}
 	var betValue float64
 	err := json.Unmarshal(qh.Msg.Value, &betValue)
 	if err != nil {
@@ -120,8 +118,37 @@ func (qh *QueueHandler) parseBetValue() (float64, error) {
 	return betValue, nil
 }
 
+func (qh *QueueHandler) parseBotQueueValue() (float64, error) {
+	var value messages.BotQueueValue
+	err := json.Unmarshal(qh.Msg.Value, &value)
+	if err != nil {
+		log.Printf("Error determining player bot queue value: %v\n", err)
+		msgBytes, _ := messages.GenerateGenericMessage("error", "Error determining player bet value")
+		qh.Client.send <- msgBytes
+		return 0, err
+	}
+	// Players only pick their bot in practice games, on paid bets bots come after the queue wait and
+	// the operator sets how they play, see the roomworker.
+	if value.BetValue != models.PracticeBetValue {
+		msgBytes, _ := messages.GenerateGenericMessage("error", "Bots can only be asked for in practice games")
+		qh.Client.send <- msgBytes
+		return 0, fmt.Errorf("queue_bot with a paid bet: %v", value.BetValue)
+	}
+	if value.Difficulty == "" {
+		value.Difficulty = engine.DefaultDifficulty
+	}
+	if !engine.IsValidDifficulty(value.Difficulty) {
+		msgBytes, _ := messages.GenerateGenericMessage("error", "Invalid bot difficulty")
+		qh.Client.send <- msgBytes
+		return 0, fmt.Errorf("invalid bot difficulty: %s", value.Difficulty)
+	}
+	qh.botDifficulty = value.Difficulty
+	return value.BetValue, nil
+}
+
 func (qh *QueueHandler) updatePlayerState(betValue float64) {
 	qh.Client.player.SelectedBet = betValue
+	qh.Client.player.BotDifficulty = qh.botDifficulty
 	qh.Client.player.Status = models.StatusInQueue
 	qh.RedisClient.UpdatePlayersInQueueSet(qh.Client.player.ID, models.StatusInQueue)
 	qh.RedisClient.UpdatePlayer(qh.Client.player)
@@ -129,7 +156,7 @@ func (qh *QueueHandler) updatePlayerState(betValue float64) {
 
 func (qh *QueueHandler) addToRedisQueue() error {
 	queueName := fmt.Sprintf("queue:%f", qh.Client.player.SelectedBet)
-	err := qh.RedisClient.RPush(queueName, qh.Client.player)
+	err := qh.RedisClient.StreamPush(queueName, qh.Client.player)
 	if err != nil {
 		log.Printf("Error pushing player to Redis queue: %v\n", err)
 		msgBytes, _ := messages.GenerateGenericMessage("error", "error adding player to queue")